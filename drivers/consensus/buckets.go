@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bucket groups findings with the same signature, which are likely the same bug.
+type Bucket struct {
+	ID        string
+	Signature string
+	Count     int      // Findings seen, including those which weren't saved
+	Examples  []string // IDs of the findings saved in the bucket's directory
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Buckets tracks the buckets in the findings directory and how many examples each keeps.
+type Buckets struct {
+	mu          sync.Mutex // Protects the buckets map
+	maxExamples int
+	buckets     map[string]*Bucket
+}
+
+// NewBuckets creates a Buckets which keeps up to maxExamples findings per bucket.
+func NewBuckets(maxExamples int) *Buckets {
+	return &Buckets{
+		maxExamples: maxExamples,
+		buckets:     make(map[string]*Bucket),
+	}
+}
+
+// FindingSignature describes a divergence by its target, which clients accepted,
+// rejected or crashed on the input and why they crashed, how the clients were grouped
+// by the vote, the class of each rejection and the top-level fields which differ, but
+// not by the input or exact outputs. Crashes map clients without a result to their
+// status, the cause of a crash or "timed-out".
+func FindingSignature(target *Target, vote *Vote, fields []string, crashes map[string]string) string {
+	statuses := make(map[string][]string)
+	var errorClasses []string
+	for _, group := range vote.Groups {
+		status := resultStatus(group.Result)
+		statuses[status] = append(statuses[status], group.Clients...)
+		if group.Result.Err {
+			class := ErrorClass(string(group.Result.Output))
+			for _, client := range group.Clients {
+				errorClasses = append(errorClasses, client+":"+class)
+			}
+		}
+	}
+	sort.Strings(errorClasses)
+	for client, cause := range crashes {
+		statuses[cause] = append(statuses[cause], client)
+	}
+
+	parts := []string{"target=" + target.Name}
+	for _, status := range []string{"accepted", "rejected", crashCauseCrashed, crashCauseOOM, crashCauseCPU, statusTimedOut} {
+		if clients := statuses[status]; len(clients) > 0 {
+			sort.Strings(clients)
+			parts = append(parts, status+"="+strings.Join(clients, ","))
+		}
+	}
+	if len(vote.Groups) > 1 {
+		// Groups are largest first, with the clients of each sorted
+		var groups []string
+		for _, group := range vote.Groups {
+			groups = append(groups, strings.Join(group.Clients, ","))
+		}
+		parts = append(parts, "groups="+strings.Join(groups, "/"))
+	}
+	if len(errorClasses) > 0 {
+		parts = append(parts, "errors="+strings.Join(errorClasses, ","))
+	}
+	if len(fields) > 0 {
+		parts = append(parts, "fields="+strings.Join(fields, ","))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Add counts a finding in the bucket for its signature, loading the bucket from disk
+// if an earlier run saved it. It sets the finding's bucket and returns true if the
+// finding should be saved as an example with SaveExample.
+func (b *Buckets) Add(finding *Finding) (*Bucket, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hash := sha256.Sum256([]byte(finding.Signature))
+	id := fmt.Sprintf("%s-%x", finding.Target, hash[:6])
+	finding.Bucket = id
+
+	bucket, exists := b.buckets[id]
+	if !exists {
+		var err error
+		bucket, err = loadBucket(id)
+		if err != nil {
+			return nil, false, err
+		}
+		if bucket == nil {
+			bucket = &Bucket{ID: id, Signature: finding.Signature, FirstSeen: finding.Time}
+		}
+		b.buckets[id] = bucket
+	}
+	bucket.Count++
+	bucket.LastSeen = finding.Time
+	if err := bucket.save(); err != nil {
+		return nil, false, err
+	}
+	return bucket, len(bucket.Examples) < b.maxExamples, nil
+}
+
+// SaveExample saves a finding which Add accepted and lists it as an example of its
+// bucket. The example is only recorded once the finding is saved.
+func (b *Buckets) SaveExample(bucket *Bucket, finding *Finding, input []byte, results map[string]Result) error {
+	if err := finding.Save(input, results); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if slices.Contains(bucket.Examples, finding.ID) {
+		return nil
+	}
+	bucket.Examples = append(bucket.Examples, finding.ID)
+	return bucket.save()
+}
+
+// Len returns the number of buckets seen in this run.
+func (b *Buckets) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.buckets)
+}
+
+// bucketPath returns the path of a bucket's metadata.
+func bucketPath(id string) string {
+	return filepath.Join(findingsDir, id, "bucket.json")
+}
+
+// loadBucket reads a bucket saved by an earlier run, or returns nil if there is none.
+func loadBucket(id string) (*Bucket, error) {
+	data, err := os.ReadFile(bucketPath(id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bucket: %w", err)
+	}
+	var bucket Bucket
+	if err := json.Unmarshal(data, &bucket); err != nil {
+		return nil, fmt.Errorf("failed to decode bucket %s: %w", id, err)
+	}
+	return &bucket, nil
+}
+
+// save writes the bucket's metadata to its directory.
+func (b *Bucket) save() error {
+	if err := os.MkdirAll(filepath.Dir(bucketPath(b.ID)), 0755); err != nil {
+		return fmt.Errorf("failed to create bucket directory: %w", err)
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bucket: %w", err)
+	}
+	if err := os.WriteFile(bucketPath(b.ID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write bucket: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFindingSignature(t *testing.T) {
+	target := &Target{Name: "bn256Add", Comparator: exactComparator{}}
+	tests := []struct {
+		name    string
+		results map[string]Result
+		fields  []string
+		crashes map[string]string
+		want    string
+	}{
+		{
+			name: "rejected",
+			results: map[string]Result{
+				"golang": {Output: []byte{1}},
+				"java":   {Output: []byte{1}},
+				"rust":   {Output: []byte("invalid input length"), Err: true},
+			},
+			want: "target=bn256Add; accepted=golang,java; rejected=rust; groups=golang,java/rust; errors=rust:length",
+		},
+		{
+			// Same statuses as above, but the accepting clients disagree
+			name: "partition",
+			results: map[string]Result{
+				"golang": {Output: []byte{1}},
+				"java":   {Output: []byte{2}},
+				"rust":   {Output: []byte("invalid input length"), Err: true},
+			},
+			want: "target=bn256Add; accepted=golang,java; rejected=rust; groups=golang/java/rust; errors=rust:length",
+		},
+		{
+			name: "fields",
+			results: map[string]Result{
+				"golang": {Output: []byte{1}},
+				"java":   {Output: []byte{1}},
+				"rust":   {Output: []byte{2}},
+			},
+			fields: []string{"balances", "validators"},
+			want:   "target=bn256Add; accepted=golang,java,rust; groups=golang,java/rust; fields=balances,validators",
+		},
+		{
+			name: "crashed",
+			results: map[string]Result{
+				"golang": {Output: []byte{1}},
+				"java":   {Output: []byte{1}},
+			},
+			crashes: map[string]string{"rust": crashCauseOOM, "nim": crashCauseCrashed},
+			want:    "target=bn256Add; accepted=golang,java; crashed=nim; oom=rust",
+		},
+	}
+	for _, test := range tests {
+		vote := NewVote(target.Comparator, test.results)
+		if got := FindingSignature(target, vote, test.fields, test.crashes); got != test.want {
+			t.Errorf("%s: FindingSignature() = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestBucketsAdd(t *testing.T) {
+	testChdir(t)
+	newFinding := func(id string, signature string) *Finding {
+		return &Finding{ID: id, Target: "sha", Signature: signature, Input: findingInputFile, Time: time.Now()}
+	}
+
+	buckets := NewBuckets(2)
+	var ids []string
+	for i := 0; i < 4; i++ {
+		finding := newFinding(fmt.Sprintf("sha-%d", i), "target=sha; accepted=a; rejected=b")
+		bucket, save, err := buckets.Add(finding)
+		if err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if want := i < 2; save != want {
+			t.Errorf("Add(%s) saves %v, want %v", finding.ID, save, want)
+		}
+		if bucket.Count != i+1 || finding.Bucket != bucket.ID {
+			t.Errorf("Add(%s) = bucket %s with count %d", finding.ID, bucket.ID, bucket.Count)
+		}
+		if save {
+			if err := buckets.SaveExample(bucket, finding, testInput(8), nil); err != nil {
+				t.Fatalf("SaveExample failed: %v", err)
+			}
+		}
+		ids = append(ids, bucket.ID)
+	}
+	if ids[0] != ids[3] {
+		t.Errorf("findings with the same signature are in buckets %s and %s", ids[0], ids[3])
+	}
+
+	other, save, err := buckets.Add(newFinding("sha-4", "target=sha; accepted=b; rejected=a"))
+	if err != nil || !save || other.ID == ids[0] {
+		t.Errorf("Add of another signature = %v, %v, %v", other, save, err)
+	}
+	if buckets.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", buckets.Len())
+	}
+	// Not saved, so it isn't an example
+	if len(other.Examples) != 0 {
+		t.Errorf("examples before saving = %v", other.Examples)
+	}
+
+	// A later run continues the count and keeps the examples saved so far
+	bucket, save, err := NewBuckets(2).Add(newFinding("sha-5", "target=sha; accepted=a; rejected=b"))
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if save || bucket.Count != 5 || !slices.Equal(bucket.Examples, []string{"sha-0", "sha-1"}) {
+		t.Errorf("Add in a later run = count %d, examples %v, save %v", bucket.Count, bucket.Examples, save)
+	}
+}
+
+func TestBucketsSaveExampleFails(t *testing.T) {
+	testChdir(t)
+	buckets := NewBuckets(2)
+	finding := &Finding{ID: "sha-0", Target: "sha", Signature: "target=sha; accepted=a; rejected=b",
+		Input: findingInputFile, Time: time.Now()}
+	bucket, _, err := buckets.Add(finding)
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	// A file where the finding's directory should be makes saving it fail
+	if err := os.WriteFile(finding.Dir(), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := buckets.SaveExample(bucket, finding, testInput(8), nil); err == nil {
+		t.Fatal("SaveExample succeeded")
+	}
+	loaded, err := loadBucket(bucket.ID)
+	if err != nil || loaded.Count != 1 || len(loaded.Examples) != 0 {
+		t.Errorf("bucket after a failed save = %+v, %v", loaded, err)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// responseErrorFlag is set in a processor's response size when the output is an error
+// message rather than a result.
+const responseErrorFlag = 1 << 31
+
+// Result is what a processor returned for an input.
+type Result struct {
+	Output []byte // Result, or the error message if the input was rejected
+	Err    bool   // The processor rejected the input
+
+	Duration time.Duration // Time from sending the input to the response, measured by the driver
+}
+
+// String formats the result for reports, shortening long outputs.
+func (r Result) String() string {
+	if r.Err {
+		return fmt.Sprintf("error %q", r.Output)
+	}
+	return traceBytes(r.Output)
+}
+
+// Comparator decides whether results from different processors are equivalent. Results
+// are equivalent if their keys are equal.
+type Comparator interface {
+	Name() string
+	Key(result Result) []byte
+}
+
+// comparators maps comparator names to the built-in comparators.
+var comparators = map[string]Comparator{
+	"exact":       exactComparator{},
+	"status":      statusComparator{},
+	"error-class": errorClassComparator{},
+	"hash":        hashComparator{},
+}
+
+// lookupComparator returns the comparator with the given name.
+func lookupComparator(name string) (Comparator, error) {
+	comparator, ok := comparators[name]
+	if !ok {
+		var names []string
+		for name := range comparators {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown comparator %q (known: %v)", name, names)
+	}
+	return comparator, nil
+}
+
+// statusKey is the key prefix which separates accepted and rejected inputs.
+func statusKey(result Result) []byte {
+	if result.Err {
+		return []byte("rejected:")
+	}
+	return []byte("accepted:")
+}
+
+// exactComparator requires the same status and the same bytes, including error messages.
+type exactComparator struct{}
+
+func (exactComparator) Name() string { return "exact" }
+
+func (exactComparator) Key(result Result) []byte {
+	return append(statusKey(result), result.Output...)
+}
+
+// statusComparator only requires processors to agree on accepting or rejecting the input.
+type statusComparator struct{}
+
+func (statusComparator) Name() string { return "status" }
+
+func (statusComparator) Key(result Result) []byte {
+	return statusKey(result)
+}
+
+// errorClassComparator requires the same output for accepted inputs, and the same class
+// of error for rejected ones, since clients word their errors differently.
+type errorClassComparator struct{}
+
+func (errorClassComparator) Name() string { return "error-class" }
+
+func (errorClassComparator) Key(result Result) []byte {
+	if result.Err {
+		return append(statusKey(result), ErrorClass(string(result.Output))...)
+	}
+	return append(statusKey(result), result.Output...)
+}
+
+// hashComparator requires the same status and compares outputs by their sha256, for
+// targets whose outputs are large, such as post-states.
+type hashComparator struct{}
+
+func (hashComparator) Name() string { return "hash" }
+
+func (hashComparator) Key(result Result) []byte {
+	if result.Err {
+		return statusKey(result)
+	}
+	hash := sha256.Sum256(result.Output)
+	return append(statusKey(result), hash[:]...)
+}
+
+// errorClasses maps words found in error messages to a class. They are checked in order,
+// so more specific words come first: a point whose coordinate is not in the field is a
+// field error.
+var errorClasses = []struct {
+	class string
+	words []string
+}{
+	{"subgroup", []string{"subgroup"}},
+	{"field", []string{"field", "fp", "modulus", "coordinate"}},
+	{"curve", []string{"curve", "point", "infinity"}},
+	{"length", []string{"length", "size", "short", "long", "bytes"}},
+	{"signature", []string{"signature", "recover", "ecrecover"}},
+	{"kzg", []string{"kzg", "proof", "blob", "versioned", "commitment"}},
+	{"gas", []string{"gas"}},
+}
+
+// errorWords splits an error message into lower case words, so "NotOnCurve",
+// "NOT_ON_CURVE" and "not on curve" have the same words.
+func errorWords(message string) []string {
+	return strings.FieldsFunc(snakeCase(message), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ErrorClass normalizes an error message to a coarse class by the whole words it contains.
+func ErrorClass(message string) string {
+	words := errorWords(message)
+	for _, entry := range errorClasses {
+		for _, word := range entry.words {
+			if slices.Contains(words, word) {
+				return entry.class
+			}
+		}
+	}
+	return "other"
+}
+
+// snakeCase converts a Go name to snake case, e.g. ETH1Data to eth1_data and
+// BLSToExecutionChanges to bls_to_execution_changes.
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			previous := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
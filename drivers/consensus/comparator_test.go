@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		// go-ethereum and gnark-crypto
+		{"invalid input length", "length"},
+		{"invalid field element top bytes", "field"},
+		{"g1 point is not on correct subgroup", "subgroup"},
+		{"bn256: coordinate exceeds modulus", "field"},
+		{"bn256: malformed point", "curve"},
+		{"invalid point: point is not on curve", "curve"},
+		{"out of gas", "gas"},
+		{"invalid fp.Element encoding", "field"},
+		// revm, as Display and Debug strings
+		{"field point not a member", "field"},
+		{"Bn128FieldPointNotAMember", "field"},
+		{"failed to create affine g point", "curve"},
+		{"Bn128PairLength", "length"},
+		{"mismatched blob version", "kzg"},
+		{"BlobVerifyKzgProofFailed", "kzg"},
+		{"G1 addition input should be 256 bytes, was 100", "length"},
+		// besu
+		{"java.lang.IllegalArgumentException: Point coordinate not in field", "field"},
+		{"Invalid input length", "length"},
+		{"BLS12_G1_POINT_NOT_IN_SUBGROUP", "subgroup"},
+		{"Unknown method: 'sha'", "other"},
+		// Words must match whole
+		{"input does not belong to the precompile", "other"},
+		{"curves and points", "other"},
+		{"pointer is nil", "other"},
+		{"", "other"},
+	}
+	for _, test := range tests {
+		if got := ErrorClass(test.message); got != test.want {
+			t.Errorf("ErrorClass(%q) = %s, want %s", test.message, got, test.want)
+		}
+	}
+}
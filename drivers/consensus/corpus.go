@@ -56,18 +56,18 @@ func (fc *FileCache) ReadFile(path string) ([]byte, error) {
 // Global cache instance
 var fileCache = NewFileCache()
 
-// Get function with caching
-func Get(fork string, object string, seed int64) ([]byte, error) {
+// CorpusPath returns the path of the corpus entry picked for a seed.
+func CorpusPath(fork string, object string, seed int64) (string, error) {
 	dir := filepath.Join("corpus", fork, object)
 
 	// Get files to choose from
 	files, err := listFiles(dir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list files: %w", err)
+		return "", fmt.Errorf("failed to list files: %w", err)
 	}
 
 	if len(files) == 0 {
-		return nil, fmt.Errorf("no files found in directory: %s", dir)
+		return "", fmt.Errorf("no files found in directory: %s", dir)
 	}
 
 	// Pick a random file
@@ -76,7 +76,15 @@ func Get(fork string, object string, seed int64) ([]byte, error) {
 	file := files[randomIndex]
 
 	// Construct the full file path
-	filePath := filepath.Join(dir, file)
+	return filepath.Join(dir, file), nil
+}
+
+// Get function with caching
+func Get(fork string, object string, seed int64) ([]byte, error) {
+	filePath, err := CorpusPath(fork, object, seed)
+	if err != nil {
+		return nil, err
+	}
 
 	// Use the cache to read the file
 	data, err := fileCache.ReadFile(filePath)
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Dictionary is a list of tokens which the dictionary mutator writes into inputs.
+type Dictionary [][]byte
+
+// dictionaryMaxTokens is the most tokens written into a single input.
+const dictionaryMaxTokens = 4
+
+// consensusDictionary holds constants from the consensus specs.
+var consensusDictionary = func() Dictionary {
+	var dictionary Dictionary
+	for _, value := range interestingUint64s {
+		dictionary = append(dictionary, binary.LittleEndian.AppendUint64(nil, value))
+	}
+	dictionary = append(dictionary,
+		// Domain types, which also cover the mainnet fork versions from ALTAIR (01000000)
+		// to FULU (06000000)
+		hexBytes("00000000"), // DOMAIN_BEACON_PROPOSER
+		hexBytes("01000000"), // DOMAIN_BEACON_ATTESTER
+		hexBytes("02000000"), // DOMAIN_RANDAO
+		hexBytes("03000000"), // DOMAIN_DEPOSIT
+		hexBytes("04000000"), // DOMAIN_VOLUNTARY_EXIT
+		hexBytes("05000000"), // DOMAIN_SELECTION_PROOF
+		hexBytes("06000000"), // DOMAIN_AGGREGATE_AND_PROOF
+		hexBytes("07000000"), // DOMAIN_SYNC_COMMITTEE
+		hexBytes("08000000"), // DOMAIN_SYNC_COMMITTEE_SELECTION_PROOF
+		hexBytes("09000000"), // DOMAIN_CONTRIBUTION_AND_PROOF
+		hexBytes("0a000000"), // DOMAIN_BLS_TO_EXECUTION_CHANGE
+		hexBytes("00000001"), // DOMAIN_APPLICATION_MASK
+		// Withdrawal credential prefixes
+		hexBytes("000000000000000000000000"), // BLS_WITHDRAWAL_PREFIX
+		hexBytes("010000000000000000000000"), // ETH1_ADDRESS_WITHDRAWAL_PREFIX
+		hexBytes("020000000000000000000000"), // COMPOUNDING_WITHDRAWAL_PREFIX
+		// Compressed BLS points at infinity, as used for empty signatures
+		append([]byte{0xc0}, make([]byte, 47)...),
+		append([]byte{0xc0}, make([]byte, 95)...),
+	)
+	return dictionary
+}()
+
+// executionDictionary holds constants that precompiles check their inputs against.
+var executionDictionary = func() Dictionary {
+	var dictionary Dictionary
+	for _, modulus := range interestingModuli {
+		if len(modulus) == 48 {
+			// BLS12-381 precompiles pad field elements to 64 bytes
+			dictionary = append(dictionary, append(make([]byte, 16), modulus...))
+		} else {
+			dictionary = append(dictionary, modulus)
+		}
+	}
+	dictionary = append(dictionary,
+		make([]byte, 32),
+		hexBytes("0000000000000000000000000000000000000000000000000000000000000001"),
+		hexBytes("000000000000000000000000000000000000000000000000000000000000001b"), // ecrecover v = 27
+		hexBytes("000000000000000000000000000000000000000000000000000000000000001c"), // ecrecover v = 28
+		hexBytes("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+		hexBytes("01"), // VERSIONED_HASH_VERSION_KZG
+		append([]byte{0xc0}, make([]byte, 47)...),
+	)
+	return dictionary
+}()
+
+// LoadDictionary reads tokens from a file in the AFL/libFuzzer dictionary format.
+// Each line is a quoted token, optionally preceded by a name and "=". Quoted tokens
+// may contain \\, \" and \xNN escapes. Empty lines and lines starting with # are ignored.
+func LoadDictionary(path string) (Dictionary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dictionary: %w", err)
+	}
+	defer file.Close()
+
+	var dictionary Dictionary
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		start := strings.IndexByte(text, '"')
+		if start < 0 || len(text) < start+2 || !strings.HasSuffix(text, `"`) {
+			return nil, fmt.Errorf("%s:%d: token is not quoted", path, line)
+		}
+		token, err := unquoteToken(text[start+1 : len(text)-1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if len(token) > 0 {
+			dictionary = append(dictionary, token)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dictionary: %w", err)
+	}
+	return dictionary, nil
+}
+
+// unquoteToken decodes the escapes in a quoted dictionary token.
+func unquoteToken(text string) ([]byte, error) {
+	var token []byte
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' {
+			token = append(token, text[i])
+			continue
+		}
+		if i+1 >= len(text) {
+			return nil, fmt.Errorf("unterminated escape")
+		}
+		switch text[i+1] {
+		case '\\', '"':
+			token = append(token, text[i+1])
+			i++
+		case 'x':
+			if i+3 >= len(text) {
+				return nil, fmt.Errorf("short hex escape")
+			}
+			value, err := strconv.ParseUint(text[i+2:i+4], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hex escape: %w", err)
+			}
+			token = append(token, byte(value))
+			i += 3
+		default:
+			return nil, fmt.Errorf("unknown escape \\%c", text[i+1])
+		}
+	}
+	return token, nil
+}
+
+// tokenAlignment returns the alignment of the offsets a token is written at: its size
+// rounded down to a power of two, and at most a 32-byte word.
+func tokenAlignment(token []byte) int {
+	align := 1
+	for align*2 <= len(token) && align < 32 {
+		align *= 2
+	}
+	return align
+}
+
+// MutateDictionary inserts or overwrites a few dictionary tokens at offsets aligned to
+// their size. The input is not modified. Edits are recorded in the trace if it is not nil.
+func MutateDictionary(input []byte, dictionary Dictionary, seed int64, trace *Trace) []byte {
+	random := rand.New(rand.NewSource(seed))
+	out := append([]byte(nil), input...)
+	if len(dictionary) == 0 {
+		return out
+	}
+
+	tokens := 1 + random.Intn(dictionaryMaxTokens)
+	for i := 0; i < tokens; i++ {
+		token := dictionary[random.Intn(len(dictionary))]
+		align := tokenAlignment(token)
+		if random.Intn(2) == 0 {
+			offset := random.Intn(len(out)/align+1) * align
+			out = append(out[:offset], append(append([]byte(nil), token...), out[offset:]...)...)
+			trace.add("insert", offset, nil, token)
+		} else if len(out) >= len(token) {
+			offset := random.Intn((len(out)-len(token))/align+1) * align
+			trace.add("overwrite", offset, out[offset:offset+len(token)], token)
+			copy(out[offset:], token)
+		}
+	}
+	return out
+}
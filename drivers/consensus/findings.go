@@ -0,0 +1,251 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// findingsDir is where findings are saved, one directory per finding.
+const findingsDir = "findings"
+
+// findingInputFile is the name of the input in a finding's directory.
+const findingInputFile = "input.bin"
+
+// Causes of a client crashing on an input.
+const (
+	crashCauseCrashed = "crashed"       // Disconnected for any other reason
+	crashCauseOOM     = "oom"           // Killed for exceeding its memory limit
+	crashCauseCPU     = "cpu-exhausted" // Killed for exceeding its CPU time limit
+)
+
+// statusTimedOut is the status of a client which didn't answer within the timeout.
+const statusTimedOut = "timed-out"
+
+// ClientResult is a client's result for the input of a finding.
+type ClientResult struct {
+	Name       string
+	Version    string `json:",omitempty"`
+	Status     string // "accepted", "rejected", "timed-out" or the cause of a crash
+	Group      int    // Index of the client's group, 0 is the largest, -1 if it has no result
+	Output     string `json:",omitempty"` // File in the finding's directory with the output or error message
+	OutputHash string `json:",omitempty"` // Hex sha256 of the output
+	Duration   string `json:",omitempty"` // How long the client took, e.g. "1.2ms"
+}
+
+// Finding is a divergence between clients. It is saved as meta.json next to the input
+// and every client's output, which is enough to replay the input later.
+type Finding struct {
+	ID           string
+	Time         time.Time
+	Target       string
+	Comparator   string
+	OutputObject string `json:",omitempty"` // SSZ object type outputs were diffed as
+	Input        string // File in the finding's directory with the input
+	InputHash    string // Hex sha256 of the input
+	Seed         int64
+	Generated    bool   `json:",omitempty"` // The input was generated rather than mutated
+	CorpusFile   string `json:",omitempty"` // Corpus entry which was mutated
+	Mutators     string `json:",omitempty"` // Mutation plan, in the format of -mutator or -weights
+	Deviants     []string
+	Clients      []ClientResult
+	Trace        []Edit   `json:",omitempty"` // Edits from the corpus entry to the input
+	Diff         []string `json:",omitempty"` // Fields of each group's output which differ from the majority
+	Fields       []string `json:",omitempty"` // Sorted top-level fields which differ
+	Signature    string   // Describes the divergence, findings with the same one share a bucket
+	Bucket       string
+	Minimized    string            `json:",omitempty"` // File in the finding's directory with the minimized input
+	Crashed      []string          `json:",omitempty"` // Clients which disconnected while processing the input
+	CrashReplays map[string]string `json:",omitempty"` // Outcome of replaying the input to each crashed client once it reconnects
+	CrashLogs    map[string]string `json:",omitempty"` // File in the finding's directory with each crashed processor's output, and its replay's
+
+	Nondeterministic string        `json:",omitempty"` // Client whose result changed when the input was sent again
+	Rerun            *ClientResult `json:",omitempty"` // The client's result the second time
+
+	Slow       []string `json:",omitempty"` // Clients which took too long, compared with the others or the budget
+	SlowFactor float64  `json:",omitempty"` // Factor of the other clients' median duration the durations were compared with
+	SlowBudget string   `json:",omitempty"` // Budget the durations were compared with, e.g. "1s"
+}
+
+// runStart identifies this run in finding IDs.
+var runStart = time.Now().UTC()
+
+// NewFinding describes a divergence between the clients' results, grouped by the vote.
+// The ID is derived from the target, the start of the run, the seed and the input.
+// Every run starts from seed 0, so without the run, a later run would replace the
+// findings of earlier ones.
+func NewFinding(target *Target, seed int64, input []byte, vote *Vote, results map[string]Result,
+	versions map[string]string) *Finding {
+	inputHash := sha256.Sum256(input)
+	finding := &Finding{
+		ID:           fmt.Sprintf("%s-%s-%d-%x", target.Name, runStart.Format("20060102T150405"), seed, inputHash[:4]),
+		Time:         time.Now().UTC(),
+		Target:       target.Name,
+		Comparator:   target.Comparator.Name(),
+		OutputObject: target.Output,
+		Input:        findingInputFile,
+		InputHash:    hex.EncodeToString(inputHash[:]),
+		Seed:         seed,
+		Deviants:     vote.Deviants,
+	}
+	for index, group := range vote.Groups {
+		for _, client := range group.Clients {
+			outputHash := sha256.Sum256(results[client].Output)
+			finding.Clients = append(finding.Clients, ClientResult{
+				Name:       client,
+				Version:    versions[client],
+				Status:     resultStatus(results[client]),
+				Group:      index,
+				Output:     client + ".out",
+				OutputHash: hex.EncodeToString(outputHash[:]),
+			})
+		}
+	}
+	sort.Slice(finding.Clients, func(i, j int) bool {
+		return finding.Clients[i].Name < finding.Clients[j].Name
+	})
+	return finding
+}
+
+// addCrashed records the clients which crashed on the input, with the cause of each crash.
+func (f *Finding) addCrashed(crashed []*Client, causes map[string]string) {
+	for _, client := range crashed {
+		f.Crashed = append(f.Crashed, client.Name)
+		f.Clients = append(f.Clients, ClientResult{
+			Name:    client.Name,
+			Version: client.Version,
+			Status:  causes[client.Name],
+			Group:   -1,
+		})
+	}
+	sort.Slice(f.Clients, func(i, j int) bool {
+		return f.Clients[i].Name < f.Clients[j].Name
+	})
+}
+
+// addTimedOut records the clients which didn't answer within the timeout.
+func (f *Finding) addTimedOut(timedOut []*Client) {
+	for _, client := range timedOut {
+		f.Clients = append(f.Clients, ClientResult{
+			Name:    client.Name,
+			Version: client.Version,
+			Status:  statusTimedOut,
+			Group:   -1,
+		})
+	}
+	sort.Slice(f.Clients, func(i, j int) bool {
+		return f.Clients[i].Name < f.Clients[j].Name
+	})
+}
+
+// addDurations records how long each client took to process the input.
+func (f *Finding) addDurations(results map[string]Result) {
+	for i := range f.Clients {
+		if result, ok := results[f.Clients[i].Name]; ok {
+			f.Clients[i].Duration = result.Duration.String()
+		}
+	}
+}
+
+// DiffOutputs lists the fields in which each group's output differs from the largest
+// group's, if outputs are SSZ objects, and the top-level fields among them. Rejections
+// have no output to compare.
+func DiffOutputs(target *Target, vote *Vote) ([]string, []string) {
+	if target.Output == "" || vote.Groups[0].Result.Err {
+		return nil, nil
+	}
+	majority := vote.Groups[0]
+	var lines []string
+	var fields []string
+	for _, group := range vote.Groups[1:] {
+		if group.Result.Err {
+			continue
+		}
+		diff, err := DiffSSZ(target.Fork, target.Output, majority.Result.Output, group.Result.Output)
+		if err != nil {
+			fmt.Printf("Error diffing outputs of %s: %v\n", strings.Join(group.Clients, ","), err)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s vs %s:",
+			strings.Join(majority.Clients, ","), strings.Join(group.Clients, ",")))
+		for _, line := range diff {
+			lines = append(lines, "  "+line)
+			end := strings.IndexAny(line, ".[:")
+			if end > 0 && !slices.Contains(fields, line[:end]) {
+				fields = append(fields, line[:end])
+			}
+		}
+	}
+	sort.Strings(fields)
+	return lines, fields
+}
+
+// Dir returns the directory the finding is saved to, inside its bucket's directory.
+func (f *Finding) Dir() string {
+	return filepath.Join(findingsDir, f.Bucket, f.ID)
+}
+
+// Save writes the input, every client's output and meta.json to the finding's directory.
+func (f *Finding) Save(input []byte, results map[string]Result) error {
+	dir := f.Dir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create finding directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, f.Input), input, 0644); err != nil {
+		return fmt.Errorf("failed to write finding input: %w", err)
+	}
+	for client, result := range results {
+		path := filepath.Join(dir, client+".out")
+		if err := os.WriteFile(path, result.Output, 0644); err != nil {
+			return fmt.Errorf("failed to write output of %s: %w", client, err)
+		}
+	}
+	return f.writeMeta(dir)
+}
+
+// saveCrashLog writes the output of a crashed processor to the finding's directory and
+// links it from meta.json. The name is the client's, or the client's with ".replay" for
+// the crash when the input was replayed.
+func (f *Finding) saveCrashLog(name string, crashLog []byte) error {
+	file := name + ".log"
+	if err := os.WriteFile(filepath.Join(f.Dir(), file), crashLog, 0644); err != nil {
+		return fmt.Errorf("failed to write crash log of %s: %w", name, err)
+	}
+	if f.CrashLogs == nil {
+		f.CrashLogs = make(map[string]string)
+	}
+	f.CrashLogs[name] = file
+	return f.writeMeta(f.Dir())
+}
+
+// writeMeta writes the finding's meta.json to a directory.
+func (f *Finding) writeMeta(dir string) error {
+	meta, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode finding: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "meta.json"), meta, 0644); err != nil {
+		return fmt.Errorf("failed to write finding metadata: %w", err)
+	}
+	return nil
+}
+
+// LoadFinding reads a finding's meta.json from its directory.
+func LoadFinding(dir string) (*Finding, error) {
+	meta, err := os.ReadFile(filepath.Join(dir, "meta.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read finding metadata: %w", err)
+	}
+	var finding Finding
+	if err := json.Unmarshal(meta, &finding); err != nil {
+		return nil, fmt.Errorf("failed to decode finding metadata: %w", err)
+	}
+	return &finding, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testChdir runs the rest of a test in a temporary directory, where findings are saved.
+func testChdir(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestFindingClientOutputs(t *testing.T) {
+	testChdir(t)
+	target := &Target{Name: "bn256Add", Comparator: errorClassComparator{}}
+	// besu and geth are in one group, but word their errors differently
+	results := map[string]Result{
+		"besu": {Output: []byte("Invalid input length"), Err: true},
+		"geth": {Output: []byte("invalid input length"), Err: true},
+		"reth": {Output: []byte{1, 2, 3}},
+	}
+	vote := NewVote(target.Comparator, results)
+	finding := NewFinding(target, 42, testInput(128), vote, results, map[string]string{"geth": "1.14.0"})
+	finding.Bucket = "bucket"
+	if err := finding.Save(testInput(128), results); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	for _, client := range finding.Clients {
+		result := results[client.Name]
+		outputHash := sha256.Sum256(result.Output)
+		if client.OutputHash != hex.EncodeToString(outputHash[:]) {
+			t.Errorf("%s: output hash is %s, want the hash of its own output", client.Name, client.OutputHash)
+		}
+		if client.Status != resultStatus(result) {
+			t.Errorf("%s: status = %s, want %s", client.Name, client.Status, resultStatus(result))
+		}
+		output, err := os.ReadFile(filepath.Join(finding.Dir(), client.Output))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(output) != string(result.Output) {
+			t.Errorf("%s: saved output %q, want %q", client.Name, output, result.Output)
+		}
+	}
+	if finding.Clients[0].Group != finding.Clients[1].Group {
+		t.Errorf("besu and geth are in groups %d and %d", finding.Clients[0].Group, finding.Clients[1].Group)
+	}
+}
+
+func TestLoadFinding(t *testing.T) {
+	testChdir(t)
+	target := &Target{Name: "bn256Add", Comparator: exactComparator{}}
+	results := map[string]Result{
+		"golang": {Output: []byte{1}, Duration: 3 * time.Millisecond},
+		"java":   {Output: []byte{1}, Duration: 5 * time.Millisecond},
+		"rust":   {Output: []byte("invalid input length"), Err: true},
+	}
+	vote := NewVote(target.Comparator, results)
+	finding := NewFinding(target, 7, testInput(64), vote, results, map[string]string{"java": "besu-25.1"})
+	finding.addCrashed([]*Client{{Name: "nim", Version: "1.0"}}, map[string]string{"nim": crashCauseOOM})
+	finding.addDurations(results)
+	finding.Signature = FindingSignature(target, vote, nil, map[string]string{"nim": crashCauseOOM})
+	finding.Bucket = "bn256Add-000000000000"
+	finding.Generated = true
+	if err := finding.Save(testInput(64), results); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadFinding(finding.Dir())
+	if err != nil {
+		t.Fatalf("LoadFinding failed: %v", err)
+	}
+	if !loaded.Time.Equal(finding.Time) {
+		t.Errorf("loaded time %v, want %v", loaded.Time, finding.Time)
+	}
+	loaded.Time = finding.Time
+	if !reflect.DeepEqual(loaded, finding) {
+		t.Errorf("LoadFinding = %+v, want %+v", loaded, finding)
+	}
+	input, err := os.ReadFile(filepath.Join(finding.Dir(), loaded.Input))
+	if err != nil || string(input) != string(testInput(64)) {
+		t.Errorf("saved input differs: %v", err)
+	}
+
+	if _, err := LoadFinding(t.TempDir()); err == nil {
+		t.Error("LoadFinding of an empty directory succeeded")
+	}
+}
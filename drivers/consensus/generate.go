@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"math/rand"
+
+	fuzzutils "github.com/trailofbits/go-fuzz-utils"
+)
+
+// generatorDataSize is how many random bytes a TypeProvider gets to build arguments from.
+const generatorDataSize = 16 * 1024
+
+// Generator builds inputs for a target from random values.
+type Generator interface {
+	Generate(random *rand.Rand) ([]byte, error)
+}
+
+// typedGenerator consumes random bytes through a TypeProvider to fill a value of type T,
+// then encodes it with the function.
+type typedGenerator[T any] func(args *T) []byte
+
+// Generate fills the arguments from random bytes and encodes them.
+func (encode typedGenerator[T]) Generate(random *rand.Rand) ([]byte, error) {
+	data := make([]byte, generatorDataSize)
+	random.Read(data)
+
+	provider, err := fuzzutils.NewTypeProvider(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create type provider: %w", err)
+	}
+
+	var args T
+	if err := provider.Fill(&args); err != nil {
+		return nil, fmt.Errorf("failed to fill arguments: %w", err)
+	}
+	return encode(&args), nil
+}
+
+// Generate builds an input for the target from the seed.
+func Generate(target *Target, seed int64) ([]byte, error) {
+	if target.Generator == nil {
+		return nil, fmt.Errorf("target %s has no generator", target.Name)
+	}
+	return target.Generator.Generate(rand.New(rand.NewSource(seed)))
+}
+
+// ShuffleArgs are the arguments to compute_shuffled_index.
+type ShuffleArgs struct {
+	Index uint64
+	Count uint64
+	Seed  [32]byte
+}
+
+// encodeShuffle encodes the index and count as little-endian uint64s followed by the seed.
+func encodeShuffle(args *ShuffleArgs) []byte {
+	// The spec requires index < count
+	if args.Count == 0 {
+		args.Count = 1
+	}
+	args.Index %= args.Count
+
+	out := binary.LittleEndian.AppendUint64(nil, args.Index)
+	out = binary.LittleEndian.AppendUint64(out, args.Count)
+	return append(out, args.Seed[:]...)
+}
+
+// EcrecoverArgs are the arguments to the ecrecover precompile.
+type EcrecoverArgs struct {
+	Hash [32]byte
+	V    uint8
+	R    [32]byte
+	S    [32]byte
+}
+
+func encodeEcrecover(args *EcrecoverArgs) []byte {
+	// Only 27 and 28 are valid, so stay close to them
+	v := 27 + uint64(args.V%4)
+
+	out := append([]byte(nil), args.Hash[:]...)
+	out = append(out, word(new(big.Int).SetUint64(v))...)
+	out = append(out, args.R[:]...)
+	return append(out, args.S[:]...)
+}
+
+// ModExpArgs are the arguments to the modexp precompile.
+type ModExpArgs struct {
+	Base     []byte
+	Exponent []byte
+	Modulus  []byte
+}
+
+func encodeModExp(args *ModExpArgs) []byte {
+	var out []byte
+	for _, value := range [][]byte{args.Base, args.Exponent, args.Modulus} {
+		out = append(out, word(big.NewInt(int64(len(value))))...)
+	}
+	out = append(out, args.Base...)
+	out = append(out, args.Exponent...)
+	return append(out, args.Modulus...)
+}
+
+// BN254G1 is an uncompressed BN254 G1 point.
+type BN254G1 struct {
+	X [32]byte
+	Y [32]byte
+}
+
+func (p *BN254G1) encode() []byte {
+	return append(append([]byte(nil), p.X[:]...), p.Y[:]...)
+}
+
+// BN254G2 is an uncompressed BN254 G2 point, with the imaginary part of each
+// coordinate first as the precompiles expect.
+type BN254G2 struct {
+	XImaginary [32]byte
+	XReal      [32]byte
+	YImaginary [32]byte
+	YReal      [32]byte
+}
+
+func (p *BN254G2) encode() []byte {
+	var out []byte
+	for _, element := range [][32]byte{p.XImaginary, p.XReal, p.YImaginary, p.YReal} {
+		out = append(out, element[:]...)
+	}
+	return out
+}
+
+// BN254AddArgs are the arguments to the bn256Add precompile.
+type BN254AddArgs struct {
+	A BN254G1
+	B BN254G1
+}
+
+func encodeBN254Add(args *BN254AddArgs) []byte {
+	return append(args.A.encode(), args.B.encode()...)
+}
+
+// BN254ScalarMulArgs are the arguments to the bn256ScalarMul precompile.
+type BN254ScalarMulArgs struct {
+	Point  BN254G1
+	Scalar [32]byte
+}
+
+func encodeBN254ScalarMul(args *BN254ScalarMulArgs) []byte {
+	return append(args.Point.encode(), args.Scalar[:]...)
+}
+
+// BN254PairingArgs are the arguments to the bn256Pairing precompile.
+type BN254PairingArgs struct {
+	Pairs []struct {
+		G1 BN254G1
+		G2 BN254G2
+	}
+}
+
+func encodeBN254Pairing(args *BN254PairingArgs) []byte {
+	var out []byte
+	for _, pair := range args.Pairs {
+		out = append(out, pair.G1.encode()...)
+		out = append(out, pair.G2.encode()...)
+	}
+	return out
+}
+
+// Blake2FArgs are the arguments to the blake2F precompile.
+type Blake2FArgs struct {
+	Rounds uint32
+	H      [64]byte
+	M      [128]byte
+	T      [16]byte
+	Final  bool
+}
+
+func encodeBlake2F(args *Blake2FArgs) []byte {
+	// Keep the number of rounds low enough to not slow down fuzzing
+	out := binary.BigEndian.AppendUint32(nil, args.Rounds%4096)
+	out = append(out, args.H[:]...)
+	out = append(out, args.M[:]...)
+	out = append(out, args.T[:]...)
+	if args.Final {
+		return append(out, 1)
+	}
+	return append(out, 0)
+}
+
+// PointEvaluationArgs are the arguments to the KZG point evaluation precompile.
+type PointEvaluationArgs struct {
+	VersionedHash [32]byte
+	Z             [32]byte
+	Y             [32]byte
+	Commitment    [48]byte
+	Proof         [48]byte
+}
+
+func encodePointEvaluation(args *PointEvaluationArgs) []byte {
+	out := append([]byte(nil), args.VersionedHash[:]...)
+	out = append(out, args.Z[:]...)
+	out = append(out, args.Y[:]...)
+	out = append(out, args.Commitment[:]...)
+	return append(out, args.Proof[:]...)
+}
+
+// BLSFp is a BLS12-381 base field element, which precompiles pad to 64 bytes.
+type BLSFp [48]byte
+
+func (e *BLSFp) encode() []byte {
+	return append(make([]byte, 16), e[:]...)
+}
+
+// BLSG1 is an uncompressed BLS12-381 G1 point.
+type BLSG1 struct {
+	X BLSFp
+	Y BLSFp
+}
+
+func (p *BLSG1) encode() []byte {
+	return append(p.X.encode(), p.Y.encode()...)
+}
+
+// BLSG2 is an uncompressed BLS12-381 G2 point.
+type BLSG2 struct {
+	X [2]BLSFp
+	Y [2]BLSFp
+}
+
+func (p *BLSG2) encode() []byte {
+	var out []byte
+	for _, element := range []BLSFp{p.X[0], p.X[1], p.Y[0], p.Y[1]} {
+		out = append(out, element.encode()...)
+	}
+	return out
+}
+
+// BLSG1AddArgs are the arguments to the bls12381G1Add precompile.
+type BLSG1AddArgs struct {
+	A BLSG1
+	B BLSG1
+}
+
+func encodeBLSG1Add(args *BLSG1AddArgs) []byte {
+	return append(args.A.encode(), args.B.encode()...)
+}
+
+// BLSG2AddArgs are the arguments to the bls12381G2Add precompile.
+type BLSG2AddArgs struct {
+	A BLSG2
+	B BLSG2
+}
+
+func encodeBLSG2Add(args *BLSG2AddArgs) []byte {
+	return append(args.A.encode(), args.B.encode()...)
+}
+
+// BLSG1MultiExpArgs are the arguments to the bls12381G1MultiExp precompile.
+type BLSG1MultiExpArgs struct {
+	Pairs []struct {
+		Point  BLSG1
+		Scalar [32]byte
+	}
+}
+
+func encodeBLSG1MultiExp(args *BLSG1MultiExpArgs) []byte {
+	var out []byte
+	for _, pair := range args.Pairs {
+		out = append(out, pair.Point.encode()...)
+		out = append(out, pair.Scalar[:]...)
+	}
+	return out
+}
+
+// BLSG2MultiExpArgs are the arguments to the bls12381G2MultiExp precompile.
+type BLSG2MultiExpArgs struct {
+	Pairs []struct {
+		Point  BLSG2
+		Scalar [32]byte
+	}
+}
+
+func encodeBLSG2MultiExp(args *BLSG2MultiExpArgs) []byte {
+	var out []byte
+	for _, pair := range args.Pairs {
+		out = append(out, pair.Point.encode()...)
+		out = append(out, pair.Scalar[:]...)
+	}
+	return out
+}
+
+// BLSPairingArgs are the arguments to the bls12381Pairing precompile.
+type BLSPairingArgs struct {
+	Pairs []struct {
+		G1 BLSG1
+		G2 BLSG2
+	}
+}
+
+func encodeBLSPairing(args *BLSPairingArgs) []byte {
+	var out []byte
+	for _, pair := range args.Pairs {
+		out = append(out, pair.G1.encode()...)
+		out = append(out, pair.G2.encode()...)
+	}
+	return out
+}
+
+// BLSMapG1Args are the arguments to the bls12381MapG1 precompile.
+type BLSMapG1Args struct {
+	Element BLSFp
+}
+
+func encodeBLSMapG1(args *BLSMapG1Args) []byte {
+	return args.Element.encode()
+}
+
+// BLSMapG2Args are the arguments to the bls12381MapG2 precompile.
+type BLSMapG2Args struct {
+	Element [2]BLSFp
+}
+
+func encodeBLSMapG2(args *BLSMapG2Args) []byte {
+	return append(args.Element[0].encode(), args.Element[1].encode()...)
+}
+
+// typedPrecompileGenerators maps precompile method names to generators of random
+// typed arguments.
+var typedPrecompileGenerators = map[string]Generator{
+	"ecrecover":          typedGenerator[EcrecoverArgs](encodeEcrecover),
+	"bigModExp":          typedGenerator[ModExpArgs](encodeModExp),
+	"bn256Add":           typedGenerator[BN254AddArgs](encodeBN254Add),
+	"bn256ScalarMul":     typedGenerator[BN254ScalarMulArgs](encodeBN254ScalarMul),
+	"bn256Pairing":       typedGenerator[BN254PairingArgs](encodeBN254Pairing),
+	"blake2F":            typedGenerator[Blake2FArgs](encodeBlake2F),
+	"kzgPointEvaluation": typedGenerator[PointEvaluationArgs](encodePointEvaluation),
+	"bls12381G1Add":      typedGenerator[BLSG1AddArgs](encodeBLSG1Add),
+	"bls12381G1MultiExp": typedGenerator[BLSG1MultiExpArgs](encodeBLSG1MultiExp),
+	"bls12381G2Add":      typedGenerator[BLSG2AddArgs](encodeBLSG2Add),
+	"bls12381G2MultiExp": typedGenerator[BLSG2MultiExpArgs](encodeBLSG2MultiExp),
+	"bls12381Pairing":    typedGenerator[BLSPairingArgs](encodeBLSPairing),
+	"bls12381MapG1":      typedGenerator[BLSMapG1Args](encodeBLSMapG1),
+	"bls12381MapG2":      typedGenerator[BLSMapG2Args](encodeBLSMapG2),
+}
+
+// word encodes a value as a 32-byte big-endian word.
+func word(value *big.Int) []byte {
+	return value.FillBytes(make([]byte, 32))
+}
@@ -1,94 +1,271 @@
 package main
 
 import (
-	"bytes"
-	"encoding/binary"
+	"errors"
+	"flag"
 	"fmt"
-	"net"
+	"math/rand"
 	"os"
 	"os/signal"
-	"sort"
+	"path/filepath"
+	"slices"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
-
-	"github.com/gen2brain/shm"
 )
 
-const (
-	socketName = "/tmp/eth-cl-fuzz"
-
-	maxClientNameLength = 32
+func directoryExists(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return info.IsDir(), nil
+}
 
-	inputShmKey = 1000
-	shmMaxSize  = 100 * 1024 * 1024 // 100 MiB
-	shmPerm     = 0666
-)
+// traceMutation re-derives the edits which produced the input for a seed. Mutation is
+// reproducible, so edits are only recorded when they are needed. This reuses the
+// mutators' buffers, which invalidates the previous result of the plan.
+func traceMutation(target *Target, plan *MutationPlan, seed int64) (*Trace, error) {
+	entry, err := Get(target.Fork, target.Object, seed)
+	if err != nil {
+		return nil, err
+	}
+	trace := &Trace{}
+	plan.Mutate(entry, seed, trace)
+	return trace, nil
+}
 
-type Client struct {
-	Name      string
-	Conn      net.Conn
-	ShmId     int
-	ShmBuffer []byte
-	Method    string
+// pendingCrash is a crash finding waiting for its client to reconnect.
+type pendingCrash struct {
+	finding *Finding
+	input   []byte
 }
 
-// detachAndDelete detaches and deletes a shared memory region.
-func detachAndDelete(shmId int, shmBuffer []byte) {
-	// Cleanup driver shared memory
-	if err := shm.Dt(shmBuffer); err != nil {
-		fmt.Printf("Failed to detach driver shared memory: %v\n", err)
+// confirmCrash replays a crashing input to the client which crashed on it and records
+// whether it crashes again in the finding. A second crash is recorded like the first:
+// it is counted, and a supervised processor's cause and output are saved with the finding.
+func confirmCrash(server *Server, supervisor *Supervisor, stats *Stats, clientName string, pending *pendingCrash) {
+	outcome := "crashed again"
+	if supervisor != nil {
+		supervisor.MarkInput()
 	}
-	if _, err := shm.Ctl(shmId, shm.IPC_RMID, nil); err != nil {
-		fmt.Printf("Failed to remove driver shared memory: %v\n", err)
+	sent := time.Now()
+	result, err := server.ProcessClient(clientName, pending.input)
+	if errors.Is(err, errClientTimedOut) {
+		outcome = "timed out"
+		if supervisor != nil {
+			supervisor.Restart(clientName)
+		}
+	} else if err != nil && !errors.Is(err, errClientCrashed) {
+		outcome = fmt.Sprintf("replay failed: %v", err)
+	} else if err == nil {
+		outcome = fmt.Sprintf("no crash, %s %v", resultStatus(result), result)
+	} else {
+		stats.RecordCrash(clientName)
+		if supervisor != nil {
+			if report, ok := supervisor.Crash(clientName, sent); ok {
+				outcome = fmt.Sprintf("crashed again (cause: %s)", report.Cause)
+				if err := pending.finding.saveCrashLog(clientName+".replay", report.Log); err != nil {
+					fmt.Printf("Error saving finding: %v\n", err)
+				}
+			}
+		}
 	}
-}
+	fmt.Printf("Replayed crash of %s from %s: %s\n", clientName, pending.finding.Dir(), outcome)
 
-// newSharedMemory creates a new shared memory segment.
-func newSharedMemory(shmKey int) (int, []byte, error) {
-	// Create the shared memory segment
-	shmId, err := shm.Get(shmKey, shmMaxSize, shmPerm|shm.IPC_CREAT|shm.IPC_EXCL)
-	if err != nil {
-		fmt.Printf("Error creating shared memory: %v\n", err)
-		return 0, nil, err
+	if pending.finding.CrashReplays == nil {
+		pending.finding.CrashReplays = make(map[string]string)
 	}
-
-	// Attach to the shared memory segment
-	shmBuffer, err := shm.At(shmId, 0, 0)
-	if err != nil {
-		fmt.Printf("Error attaching to shared memory: %v\n", err)
-		return 0, nil, err
+	pending.finding.CrashReplays[clientName] = outcome
+	if err := pending.finding.writeMeta(pending.finding.Dir()); err != nil {
+		fmt.Printf("Error saving finding: %v\n", err)
 	}
-
-	return shmId, shmBuffer, nil
 }
 
-func directoryExists(path string) (bool, error) {
-	info, err := os.Stat(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return false, nil
-		}
-		return false, err
+// restartTimedOut restarts the supervised processors of clients which timed out, since
+// they may never answer. The server has already disconnected them.
+func restartTimedOut(supervisor *Supervisor, timedOut []*Client) {
+	if supervisor == nil {
+		return
+	}
+	for _, client := range timedOut {
+		fmt.Printf("Restarting processor %s, it timed out\n", client.Name)
+		supervisor.Restart(client.Name)
 	}
-	return info.IsDir(), nil
 }
 
 func main() {
-	mu := &sync.Mutex{}
-	clients := make(map[string]*Client)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			os.Exit(replay(os.Args[2:]))
+		case "minimize":
+			os.Exit(minimize(os.Args[2:]))
+		}
+	}
 
-	// Initialize the corpus
-	corpusExists, err := directoryExists("corpus")
+	targetName := flag.String("target", "sha", "method for processors to run")
+	mutator := flag.String("mutator", "",
+		"comma-separated mutators to stack instead of picking one of the target's mutators per input: "+
+			"\"bytes\" flips raw bytes, \"ssz\" mutates decoded fields, \"offsets\" mutates SSZ offsets, "+
+			"\"dict\" writes dictionary tokens, \"havoc\" applies whole-input strategies")
+	weights := flag.String("weights", "",
+		"comma-separated name=weight pairs which replace the target's mutator weights")
+	generate := flag.Bool("generate", false,
+		"generate typed inputs for the target instead of mutating corpus entries")
+	precompileVectors := flag.String("precompile-vectors", "",
+		"import go-ethereum precompile vectors from this path into the execution corpus")
+	dictionaries := flag.String("dict", "",
+		"comma-separated dictionary files whose tokens are added to the target's built-in ones")
+	comparator := flag.String("comparator", "",
+		"how results are compared instead of the target's default: \"exact\" bytes, accept/reject "+
+			"\"status\", \"error-class\" of rejections, or \"hash\" of outputs")
+	output := flag.String("output", "",
+		"SSZ object type of the processors' outputs in the target's fork, e.g. BeaconState, "+
+			"to list the fields in which differing outputs differ")
+	bucketExamples := flag.Int("bucket-examples", 5,
+		"findings saved per bucket of findings with the same signature, later ones are only counted")
+	suppressionsPath := flag.String("suppress", "",
+		"JSON file of known divergences which are counted instead of reported")
+	processorsPath := flag.String("processors", "",
+		"JSON file of processors for the driver to start, log and restart")
+	recheckRate := flag.Float64("recheck", 0,
+		"fraction of inputs sent to the clients a second time to detect nondeterministic results")
+	slowFactor := flag.Float64("slow-factor", 10,
+		"save inputs which a client takes this many times longer to process than the median of the "+
+			"other clients, 0 disables")
+	slowBudget := flag.Duration("slow-budget", 0,
+		"save inputs which a client takes longer than this to process, 0 disables")
+	timeout := flag.Duration("timeout", time.Minute,
+		"disconnect clients which take longer than this to process an input, and restart them if "+
+			"supervised, 0 disables. Must be longer than -slow-budget")
+	flag.Parse()
+
+	if *timeout > 0 && *slowBudget >= *timeout {
+		fmt.Printf("The timeout (%s) must be longer than the slow budget (%s)\n", *timeout, *slowBudget)
+		os.Exit(1)
+	}
+
+	target, err := lookupTarget(*targetName)
 	if err != nil {
-		fmt.Printf("Error checking if directory exists: %v\n", err)
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if (*generate || !target.HasCorpus()) && target.Generator == nil {
+		fmt.Printf("Target %s cannot generate inputs\n", target.Name)
+		os.Exit(1)
+	}
+	if *dictionaries != "" {
+		// Copy the built-in tokens, they are shared between targets
+		target.Dictionary = append(Dictionary(nil), target.Dictionary...)
+		for _, path := range strings.Split(*dictionaries, ",") {
+			tokens, err := LoadDictionary(path)
+			if err != nil {
+				fmt.Printf("Error loading dictionary: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Loaded dictionary %s (tokens: %d)\n", path, len(tokens))
+			target.Dictionary = append(target.Dictionary, tokens...)
+		}
+	}
+	if *comparator != "" {
+		target.Comparator, err = lookupComparator(*comparator)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if *output != "" {
+		if err := target.SetOutput(*output); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	var suppressions []*Suppression
+	if *suppressionsPath != "" {
+		suppressions, err = LoadSuppressions(*suppressionsPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Loaded suppressions %s (rules: %d)\n", *suppressionsPath, len(suppressions))
+	}
+	var processors []*ProcessorConfig
+	if *processorsPath != "" {
+		processors, err = LoadProcessors(*processorsPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if *recheckRate < 0 || *recheckRate > 1 {
+		fmt.Println("-recheck must be between 0 and 1")
 		os.Exit(1)
 	}
-	if !corpusExists {
-		err := InitializeCorpus()
+	if *weights != "" {
+		target.Mutators, err = ParseMutatorWeights(*weights)
 		if err != nil {
-			fmt.Printf("Error initializing corpus: %v\n", err)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// Initialize the corpus
+	if target.HasCorpus() && !target.IsExecution() {
+		corpusExists, err := directoryExists(filepath.Join("corpus", target.Fork))
+		if err != nil {
+			fmt.Printf("Error checking if directory exists: %v\n", err)
+			os.Exit(1)
+		}
+		if !corpusExists {
+			err := InitializeCorpus()
+			if err != nil {
+				fmt.Printf("Error initializing corpus: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	// Import execution vectors
+	if *precompileVectors != "" {
+		err := ImportPrecompileVectors(*precompileVectors)
+		if err != nil {
+			fmt.Printf("Error importing precompile vectors: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Execution targets only have a corpus once vectors are imported
+	if !*generate && target.IsExecution() {
+		corpusExists, err := directoryExists(filepath.Join("corpus", target.Fork, target.Object))
+		if err != nil {
+			fmt.Printf("Error checking if directory exists: %v\n", err)
+			os.Exit(1)
+		}
+		if !corpusExists && target.Generator == nil {
+			fmt.Printf("No corpus for target %s, import vectors with -precompile-vectors\n", target.Name)
+			os.Exit(1)
+		}
+		if !corpusExists {
+			fmt.Printf("No corpus for target %s, generating inputs instead (import vectors with -precompile-vectors)\n",
+				target.Name)
+			*generate = true
+		}
+	}
+
+	// Choose how corpus entries are mutated
+	var plan *MutationPlan
+	if !*generate && target.HasCorpus() {
+		if *mutator != "" {
+			plan, err = NewStackedPlan(target, strings.Split(*mutator, ","))
+		} else {
+			plan, err = NewWeightedPlan(target, target.Mutators)
+		}
+		if err != nil {
+			fmt.Println(err)
 			os.Exit(1)
 		}
 	}
@@ -97,221 +274,319 @@ func main() {
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
 
-	inputShmId, inputShmBuffer, err := newSharedMemory(inputShmKey)
+	server, err := NewServer(target)
 	if err != nil {
-		fmt.Printf("Error creating input shm: %v\n", err)
+		fmt.Printf("Error starting server: %v\n", err)
 		os.Exit(1)
 	}
+	server.SetTimeout(*timeout)
 
-	// Create unix domain socket for small communications
-	os.Remove(socketName)
-	registrationListener, err := net.Listen("unix", socketName)
-	if err != nil {
-		fmt.Printf("Error creating Unix domain socket: %v\n", err)
-		os.Exit(1)
+	// Start the processors once the socket exists
+	var supervisor *Supervisor
+	if len(processors) > 0 {
+		supervisor, err = NewSupervisor(processors)
+		if err != nil {
+			fmt.Printf("Error starting processors: %v\n", err)
+			server.Close()
+			os.Exit(1)
+		}
+		fmt.Printf("Started processors from %s (processors: %d)\n", *processorsPath, len(processors))
 	}
 
 	// A thread for cleanup
 	go func() {
 		<-signalChan
 		fmt.Println("\nReceived interrupt")
-		mu.Lock()
-		detachAndDelete(inputShmId, inputShmBuffer)
-		for _, client := range clients {
-			client.Conn.Close()
-			detachAndDelete(client.ShmId, client.ShmBuffer)
-		}
-		mu.Unlock()
-		registrationListener.Close()
-		os.Remove(socketName)
+		server.Close()
+		if supervisor != nil {
+			supervisor.Stop()
+		}
 		fmt.Println("Goodbye!")
 		os.Exit(0)
 	}()
 
 	// A thread for status updates
-	var count int
-	var totalTime time.Duration
+	stats := NewStats()
+	buckets := NewBuckets(*bucketExamples)
+	pendingCrashes := make(map[string]*pendingCrash) // Crashes to confirm once the client reconnects
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 	go func() {
 		for range ticker.C {
-			if count != 0 {
-				average := totalTime / time.Duration(count)
-				var clientNames []string
-				for clientName := range clients {
-					clientNames = append(clientNames, clientName)
-				}
-				sort.Strings(clientNames)
-				joinedNames := strings.Join(clientNames, ",")
-				fmt.Printf("Fuzzing Time: %s, Iterations: %v, Average Iteration: %s, Clients: %v\n",
-					totalTime.Round(time.Second), count, average.Round(time.Millisecond), joinedNames)
+			snapshot := stats.Snapshot()
+			if snapshot.Iterations != 0 {
+				fmt.Print(snapshot.Report(server.ClientNames(), buckets.Len()))
 			}
 		}
 	}()
 
-	// A thread for client registrations
-	go func() {
-		for {
-			conn, err := registrationListener.Accept()
-			if err != nil {
-				// Don't print error if we close the registration listener
-				if !strings.Contains(err.Error(), "use of closed network connection") {
-					fmt.Printf("Error accepting connection: %v\n", err)
-				}
-				return
-			}
-			defer conn.Close()
+	var seed int64 = 0
 
-			clientNameBytes := make([]byte, maxClientNameLength)
-			n, err := conn.Read(clientNameBytes)
-			if err != nil {
-				fmt.Printf("Error reading client name: %v\n", err)
-				return
-			}
-			clientName := string(clientNameBytes[:n])
+	for {
+		start := time.Now()
 
-			inputShmIdBytes := make([]byte, 4)
-			binary.BigEndian.PutUint32(inputShmIdBytes, uint32(inputShmId))
-			_, err = conn.Write([]byte(inputShmIdBytes))
-			if err != nil {
-				fmt.Printf("Error writing to client %s: %v\n", clientName, err)
-				return
-			}
+		// Wait for at least one client to connect
+		if len(server.ClientNames()) == 0 {
+			fmt.Println("Waiting for a client...")
+			time.Sleep(1 * time.Second)
+			stats.ResetIterations()
+			continue
+		}
 
-			outputShmKey := inputShmKey + len(clients) + 1
-			outputShmId, clientShmBuffer, err := newSharedMemory(outputShmKey)
-			if err != nil {
-				fmt.Printf("Error creating client output shm: %v\n", err)
-				return
+		// Confirm crashes by replaying the input once the client reconnects
+		registered := server.ClientNames()
+		for clientName, pending := range pendingCrashes {
+			if slices.Contains(registered, clientName) {
+				delete(pendingCrashes, clientName)
+				confirmCrash(server, supervisor, stats, clientName, pending)
 			}
+		}
 
-			outputShmIdBytes := make([]byte, 4)
-			binary.BigEndian.PutUint32(outputShmIdBytes, uint32(outputShmId))
-			_, err = conn.Write([]byte(outputShmIdBytes))
+		var mutatedState []byte
+		if *generate || !target.HasCorpus() {
+			// Generate typed arguments
+			mutatedState, err = Generate(target, seed)
 			if err != nil {
-				fmt.Printf("Error writing to client %s: %v\n", clientName, err)
-				return
+				fmt.Println(err)
+				seed++
+				continue
 			}
-
-			_, err = conn.Write([]byte("sha"))
+		} else {
+			// Get a random corpus entry
+			state, err := Get(target.Fork, target.Object, seed)
 			if err != nil {
-				fmt.Printf("Error writing to client %s: %v\n", clientName, err)
-				return
+				fmt.Println(err)
+				seed++
+				continue
 			}
 
-			mu.Lock()
-			if _, exists := clients[clientName]; !exists {
-				clients[clientName] = &Client{
-					Name:      clientName,
-					Conn:      conn,
-					ShmId:     outputShmId,
-					ShmBuffer: clientShmBuffer,
-					Method:    "sha",
-				}
-				fmt.Printf("Registered new client: %s\n", clientName)
-			}
-			mu.Unlock()
+			// Mutate the entry
+			mutatedState = plan.Mutate(state, seed, nil)
 		}
-	}()
-
-	var seed int64 = 0
 
-	for {
-		start := time.Now()
-
-		// Wait for at least one client to connect
-		mu.Lock()
-		numClients := len(clients)
-		mu.Unlock()
-		if numClients == 0 {
-			fmt.Println("Waiting for a client...")
-			time.Sleep(1 * time.Second)
-			count = 0
-			totalTime = 0
-			continue
+		// Processors which used half of their CPU time are restarted, so one which runs
+		// out did so on this input
+		if supervisor != nil {
+			for _, clientName := range supervisor.Spent() {
+				fmt.Printf("Restarting processor %s, it used half of its CPU time limit\n", clientName)
+				supervisor.Restart(clientName)
+				server.Disconnect(clientName)
+			}
+			supervisor.MarkInput()
+		}
+		sent := time.Now()
+		results, crashed, timedOut := server.Process(mutatedState)
+		restartTimedOut(supervisor, timedOut)
+
+		// Send a sample of inputs again, a client's result shouldn't change. A client which
+		// crashes the second time crashed on the input, like any other crash.
+		var changed []*nondeterminism
+		if len(crashed) == 0 && rand.Float64() < *recheckRate {
+			results = copyResults(results)
+			if supervisor != nil {
+				supervisor.MarkInput()
+			}
+			sent = time.Now()
+			var recheckTimedOut []*Client
+			changed, crashed, recheckTimedOut = checkDeterminism(server, target, seed, mutatedState, results)
+			restartTimedOut(supervisor, recheckTimedOut)
+			for _, client := range crashed {
+				fmt.Printf("Client %s crashed when the input was sent again\n", client.Name)
+				delete(results, client.Name)
+			}
 		}
 
-		// Generate a random state
-		state, err := Get("electra", "BeaconState", seed)
-		if err != nil {
-			fmt.Println(err)
-			continue
+		vote := NewVote(target.Comparator, results)
+		if !vote.Agreed() {
+			stats.RecordDivergence(vote)
+		}
+		var suppression *Suppression
+		if !vote.Agreed() && len(crashed) == 0 {
+			suppression = matchSuppression(suppressions, target.Name, vote, results)
 		}
+		if suppression != nil {
+			stats.RecordSuppressed(suppression.Name)
+		} else if !vote.Agreed() || len(crashed) > 0 {
+			// Supervised processors are classified by why they exited
+			causes := make(map[string]string)
+			crashLogs := make(map[string][]byte)
+			for _, client := range crashed {
+				causes[client.Name] = crashCauseCrashed
+				if supervisor == nil {
+					continue
+				}
+				if report, ok := supervisor.Crash(client.Name, sent); ok {
+					causes[client.Name] = report.Cause
+					crashLogs[client.Name] = report.Log
+				}
+			}
 
-		// Mutate the state
-		mutatedState := Mutate(state, seed)
+			finding := NewFinding(target, seed, mutatedState, vote, results, server.Versions())
+			finding.addCrashed(crashed, causes)
+			finding.addDurations(results)
+			if !vote.Agreed() {
+				finding.Diff, finding.Fields = DiffOutputs(target, vote)
+			}
+			finding.Signature = FindingSignature(target, vote, finding.Fields, causes)
 
-		// Copy the mutated state into the input buffer
-		copy(inputShmBuffer, mutatedState)
+			stats.RecordDeviations(append(vote.Deviants, finding.Crashed...))
 
-		mu.Lock()
-		wg := &sync.WaitGroup{}
-		muResult := &sync.Mutex{}
-		results := make(map[string][]byte)
-		for _, client := range clients {
-			wg.Add(1)
-			go func(client *Client) {
-				defer wg.Done()
+			bucket, save, err := buckets.Add(finding)
+			if err != nil {
+				fmt.Printf("Error adding finding to bucket: %v\n", err)
+			} else if !save {
+				// Only the first examples of a bucket are reported in full
+				fmt.Printf("Values are different, bucket %s (count: %d)\n", bucket.ID, bucket.Count)
+			} else {
+				for _, clientName := range finding.Crashed {
+					fmt.Printf("Client %s crashed (cause: %s)\n", clientName, causes[clientName])
+				}
+				if !vote.Agreed() {
+					fmt.Printf("Values are different (comparator: %s):\n%s", target.Comparator.Name(), vote)
+				}
+				if len(finding.Diff) > 0 {
+					fmt.Printf("Differing fields:\n%s\n", strings.Join(finding.Diff, "\n"))
+				}
+				if *generate || !target.HasCorpus() {
+					finding.Generated = true
+				} else {
+					finding.Mutators = plan.String()
+					finding.CorpusFile, err = CorpusPath(target.Fork, target.Object, seed)
+					if err != nil {
+						fmt.Printf("Error finding corpus entry: %v\n", err)
+					}
 
-				// Send the input size to the client
-				sizeBytes := make([]byte, 4)
-				binary.BigEndian.PutUint32(sizeBytes, uint32(len(mutatedState)))
-				_, err := client.Conn.Write([]byte(sizeBytes))
-				if err != nil {
-					if strings.Contains(err.Error(), "broken pipe") {
-						fmt.Printf("Client disconnected: %v\n", client.Name)
+					// Tracing reuses the mutation buffer, so keep the input
+					mutatedState = append([]byte(nil), mutatedState...)
+					trace, err := traceMutation(target, plan, seed)
+					if err != nil {
+						fmt.Printf("Error tracing mutation: %v\n", err)
 					} else {
-						fmt.Printf("Error writing to client %s: %v\n", client.Name, err)
+						fmt.Printf("Seed: %d, edits to the corpus entry:\n%s", seed, trace)
+						finding.Trace = trace.Edits
 					}
-					detachAndDelete(client.ShmId, client.ShmBuffer)
-					delete(clients, client.Name)
-					return
 				}
+				if err := buckets.SaveExample(bucket, finding, mutatedState, results); err != nil {
+					fmt.Printf("Error saving finding: %v\n", err)
+				} else {
+					fmt.Printf("Saved finding to %s (bucket: %s)\n", finding.Dir(), bucket.Signature)
+					for clientName, crashLog := range crashLogs {
+						if err := finding.saveCrashLog(clientName, crashLog); err != nil {
+							fmt.Printf("Error saving finding: %v\n", err)
+						}
+					}
+					for _, clientName := range finding.Crashed {
+						pendingCrashes[clientName] = &pendingCrash{
+							finding: finding,
+							input:   append([]byte(nil), mutatedState...),
+						}
+					}
+				}
+			}
+		}
 
-				// Wait for a response size
-				responseSizeBytes := make([]byte, 4)
-				_, err = client.Conn.Read(responseSizeBytes)
-				if err != nil {
-					if !strings.Contains(err.Error(), "EOF") {
-						fmt.Printf("Error reading response from client %s: %v\n", client.Name, err)
+		// Slow inputs are denial of service vectors, even if the clients agree
+		// Clients which timed out are the slowest of all
+		slow := slowClients(results, *slowFactor, *slowBudget)
+		for _, client := range timedOut {
+			slow = append(slow, client.Name)
+		}
+		slices.Sort(slow)
+		if len(slow) > 0 {
+			stats.RecordSlow(slow)
+
+			finding := NewFinding(target, seed, mutatedState, vote, results, server.Versions())
+			finding.addTimedOut(timedOut)
+			finding.addDurations(results)
+			finding.Slow = slow
+			finding.SlowFactor = *slowFactor
+			if *slowBudget > 0 {
+				finding.SlowBudget = slowBudget.String()
+			}
+			finding.Signature = SlowSignature(target, vote, slow, timedOut)
+			bucket, save, err := buckets.Add(finding)
+			if err != nil {
+				fmt.Printf("Error adding finding to bucket: %v\n", err)
+			} else if !save {
+				fmt.Printf("Clients were slow, bucket %s (count: %d)\n", bucket.ID, bucket.Count)
+			} else {
+				var durations []string
+				for _, client := range finding.Clients {
+					if client.Status == statusTimedOut {
+						durations = append(durations, client.Name+"="+client.Status)
+					} else {
+						durations = append(durations, client.Name+"="+client.Duration)
 					}
-					detachAndDelete(client.ShmId, client.ShmBuffer)
-					delete(clients, client.Name)
-					return
 				}
-				responseSize := binary.BigEndian.Uint32(responseSizeBytes)
-
-				// Write the response to the results map
-				muResult.Lock()
-				results[client.Name] = client.ShmBuffer[:responseSize]
-				muResult.Unlock()
-			}(client)
-		}
-		wg.Wait()
-		mu.Unlock()
-
-		same := true
-		var first []byte
-		firstResultSet := false
-		for _, result := range results {
-			if !firstResultSet {
-				first = result
-				firstResultSet = true
-			} else if !bytes.Equal(result, first) {
-				same = false
-				break
+				fmt.Printf("Clients were slow: %s (durations: %s)\n",
+					strings.Join(slow, ","), strings.Join(durations, ","))
+				if *generate || !target.HasCorpus() {
+					finding.Generated = true
+				} else {
+					finding.Mutators = plan.String()
+					finding.CorpusFile, err = CorpusPath(target.Fork, target.Object, seed)
+					if err != nil {
+						fmt.Printf("Error finding corpus entry: %v\n", err)
+					}
+				}
+				if err := buckets.SaveExample(bucket, finding, mutatedState, results); err != nil {
+					fmt.Printf("Error saving finding: %v\n", err)
+				} else {
+					fmt.Printf("Saved finding to %s (bucket: %s)\n", finding.Dir(), bucket.Signature)
+				}
 			}
 		}
-		if !same {
-			fmt.Println("Values are different:")
+
+		// Check results against known vectors
+		if target.IsExecution() {
 			for client, result := range results {
-				fmt.Printf("Key: %v, Value: %x\n", client, result)
+				mismatches, err := oracle.Check(target.Name, mutatedState, result)
+				if err != nil {
+					fmt.Printf("Error checking expected results: %v\n", err)
+					break
+				}
+				for _, name := range mismatches {
+					fmt.Printf("Client %s disagrees with expected output of vector %s: %v\n",
+						client, name, result)
+				}
+			}
+		}
+
+		// Clients whose result changed when the input was sent again
+		for _, found := range changed {
+			finding := found.finding
+			stats.RecordNondeterministic(finding.Nondeterministic)
+
+			bucket, save, err := buckets.Add(finding)
+			if err != nil {
+				fmt.Printf("Error adding finding to bucket: %v\n", err)
+			} else if !save {
+				fmt.Printf("Result of %s changed when rechecked, bucket %s (count: %d)\n",
+					finding.Nondeterministic, bucket.ID, bucket.Count)
+			} else {
+				fmt.Printf("Result of %s changed when rechecked: %v, then %v\n",
+					finding.Nondeterministic, found.runs.Groups[0].Result, found.runs.Groups[1].Result)
+				if len(finding.Diff) > 0 {
+					fmt.Printf("Differing fields:\n%s\n", strings.Join(finding.Diff, "\n"))
+				}
+				if *generate || !target.HasCorpus() {
+					finding.Generated = true
+				} else {
+					finding.Mutators = plan.String()
+					finding.CorpusFile, err = CorpusPath(target.Fork, target.Object, seed)
+					if err != nil {
+						fmt.Printf("Error finding corpus entry: %v\n", err)
+					}
+				}
+				if err := buckets.SaveExample(bucket, finding, mutatedState, found.results); err != nil {
+					fmt.Printf("Error saving finding: %v\n", err)
+				} else {
+					fmt.Printf("Saved finding to %s (bucket: %s)\n", finding.Dir(), bucket.Signature)
+				}
 			}
 		}
 
-		duration := time.Since(start)
-		totalTime += duration
-		count++
+		stats.RecordIteration(target.Name, time.Since(start), results, crashed, timedOut)
 		seed++
 	}
 }
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// findingMinimizedFile is the name of the minimized input in a finding's directory.
+const findingMinimizedFile = "minimized.bin"
+
+// Exit codes of the minimize subcommand.
+const (
+	minimizeSaved         = 0 // The minimized input was saved
+	minimizeNotReproduced = 1 // The finding no longer diverges, so there is nothing to minimize
+	minimizeFailed        = 2 // The finding couldn't be minimized
+)
+
+// sszParallelLists maps a list to the lists which hold a value for each of its elements,
+// such as a balance per validator. They are shrunk together, so they stay the same length.
+var sszParallelLists = map[string][]string{
+	"validators": {"balances", "inactivity_scores", "previous_epoch_participation", "current_epoch_participation"},
+}
+
+// minimizer shrinks an input while the processors still diverge the same way.
+type minimizer struct {
+	server      *Server
+	target      *Target
+	clientNames []string
+	timeout     time.Duration // How long to wait for crashed clients to reconnect
+	signature   string        // Signature of the divergence which must be preserved
+	rerun       bool          // Send inputs to each client twice, for a client whose result changed
+	slow        bool          // Compare durations rather than results, for slow clients
+	slowFactor  float64       // Factor of the other clients' median a slow client must exceed
+	slowBudget  time.Duration // Duration a slow client must exceed
+	tests       int           // Inputs sent to processors so far
+	maxTests    int
+}
+
+// minimize shrinks a finding's input against live processors and saves the smallest
+// input which still diverges with the same signature next to the original.
+func minimize(args []string) int {
+	flags := flag.NewFlagSet("minimize", flag.ExitOnError)
+	clientList := flags.String("clients", "",
+		"comma-separated processors to wait for instead of the finding's clients")
+	timeout := flags.Duration("timeout", 5*time.Minute, "how long to wait for processors")
+	maxTests := flags.Int("max-tests", 10000, "most inputs to send to processors")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s minimize [flags] <finding-dir>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return minimizeFailed
+	}
+	dir := flags.Arg(0)
+
+	finding, err := LoadFinding(dir)
+	if err != nil {
+		fmt.Println(err)
+		return minimizeFailed
+	}
+	input, err := os.ReadFile(filepath.Join(dir, finding.Input))
+	if err != nil {
+		fmt.Printf("Error reading finding input: %v\n", err)
+		return minimizeFailed
+	}
+	var clientNames []string
+	for _, client := range finding.Clients {
+		clientNames = append(clientNames, client.Name)
+	}
+	if *clientList != "" {
+		clientNames = strings.Split(*clientList, ",")
+	}
+	target, err := lookupTarget(finding.Target)
+	if err != nil {
+		fmt.Println(err)
+		return minimizeFailed
+	}
+	target.Comparator, err = lookupComparator(finding.Comparator)
+	if err != nil {
+		fmt.Println(err)
+		return minimizeFailed
+	}
+	if finding.OutputObject != "" {
+		if err := target.SetOutput(finding.OutputObject); err != nil {
+			fmt.Println(err)
+			return minimizeFailed
+		}
+	}
+
+	var slowFactor float64
+	var slowBudget time.Duration
+	if len(finding.Slow) > 0 {
+		slowFactor, slowBudget, err = finding.slowLimits()
+		if err != nil {
+			fmt.Println(err)
+			return minimizeFailed
+		}
+	}
+
+	server, err := connectClients(target, clientNames, *timeout)
+	if err != nil {
+		fmt.Println(err)
+		return minimizeFailed
+	}
+	defer server.Close()
+
+	// The processors may have changed since the finding, so take the signature from them
+	m := &minimizer{
+		server:      server,
+		target:      target,
+		clientNames: clientNames,
+		timeout:     *timeout,
+		rerun:       finding.Nondeterministic != "",
+		slow:        len(finding.Slow) > 0,
+		slowFactor:  slowFactor,
+		slowBudget:  slowBudget,
+		maxTests:    *maxTests,
+	}
+	m.signature = m.divergence(input)
+	if m.signature == "" {
+		fmt.Println("The finding no longer reproduces")
+		return minimizeNotReproduced
+	}
+	fmt.Printf("Minimizing %d bytes (signature: %s)\n", len(input), m.signature)
+
+	minimized := input
+	if target.HasCorpus() && !target.IsExecution() {
+		minimized = shrinkSSZ(target, minimized, m.exhausted, m.interesting)
+	}
+	minimized = shrinkBytes(minimized, m.exhausted, m.interesting)
+
+	path := filepath.Join(dir, findingMinimizedFile)
+	if err := os.WriteFile(path, minimized, 0644); err != nil {
+		fmt.Printf("Error writing minimized input: %v\n", err)
+		return minimizeFailed
+	}
+	finding.Minimized = findingMinimizedFile
+	if err := finding.writeMeta(dir); err != nil {
+		fmt.Println(err)
+		return minimizeFailed
+	}
+	fmt.Printf("Saved minimized input to %s (%d bytes, from %d, tests: %d)\n",
+		path, len(minimized), len(input), m.tests)
+	return minimizeSaved
+}
+
+// divergence sends an input to the clients and returns the signature of their divergence,
+// or an empty string if they agree or a client returned no result. Crashes are part of
+// the signature, and crashed clients are waited for before returning. A client whose
+// result changed is only compared with itself. For slow clients, the signature names
+// the clients which are still slow, and an input on which none is slow doesn't diverge.
+func (m *minimizer) divergence(input []byte) string {
+	if m.rerun {
+		return m.changed(input)
+	}
+	m.tests++
+	results, crashed := processClients(m.server, m.clientNames, input)
+	if len(crashed) > 0 {
+		m.waitForCrashed()
+	}
+	if len(results)+len(crashed) != len(m.clientNames) {
+		return ""
+	}
+	vote := NewVote(m.target.Comparator, results)
+	if m.slow {
+		slow := slowClients(results, m.slowFactor, m.slowBudget)
+		if len(slow) == 0 || len(crashed) > 0 {
+			return ""
+		}
+		return SlowSignature(m.target, vote, slow, nil)
+	}
+	if vote.Agreed() && len(crashed) == 0 {
+		return ""
+	}
+	var fields []string
+	if !vote.Agreed() {
+		_, fields = DiffOutputs(m.target, vote)
+	}
+	crashes := make(map[string]string)
+	for _, clientName := range crashed {
+		crashes[clientName] = crashCauseCrashed
+	}
+	return FindingSignature(m.target, vote, fields, crashes)
+}
+
+// changed sends an input to each client twice and returns the signature of the first
+// client whose result changed, or an empty string if none did or a client returned no
+// result.
+func (m *minimizer) changed(input []byte) string {
+	m.tests++
+	for _, clientName := range m.clientNames {
+		first, second, err := sendTwice(m.server, clientName, input)
+		if err != nil {
+			if errors.Is(err, errClientCrashed) {
+				m.waitForCrashed()
+			}
+			return ""
+		}
+		if resultChanged(first, second) {
+			return newNondeterminism(m.target, 0, input, clientName, "", first, second).finding.Signature
+		}
+	}
+	return ""
+}
+
+// waitForCrashed waits for crashed clients to reconnect, and stops minimizing if they don't.
+func (m *minimizer) waitForCrashed() {
+	if err := waitForClients(m.server, m.clientNames, m.timeout); err != nil {
+		// Stop minimizing, the crashed clients are gone
+		fmt.Println(err)
+		m.tests = m.maxTests
+	}
+}
+
+// interesting returns true if an input still diverges with the original signature.
+func (m *minimizer) interesting(input []byte) bool {
+	return m.divergence(input) == m.signature
+}
+
+// exhausted returns true once the most inputs have been sent.
+func (m *minimizer) exhausted() bool {
+	return m.tests >= m.maxTests
+}
+
+// shrinkBytes removes ranges of bytes with delta debugging, keeping removals after
+// which the input is still interesting.
+func shrinkBytes(input []byte, stop func() bool, interesting func([]byte) bool) []byte {
+	current := input
+	ddmin(len(input), stop, func(start int, end int) bool {
+		candidate := append(append([]byte(nil), current[:start]...), current[end:]...)
+		if !interesting(candidate) {
+			return false
+		}
+		current = candidate
+		fmt.Printf("Reduced to %d bytes\n", len(current))
+		return true
+	})
+	return current
+}
+
+// shrinkSSZ removes list elements, such as validators, from the input decoded as the
+// target's object with delta debugging, as long as it is still interesting. Lists are
+// revisited until none shrinks.
+func shrinkSSZ(target *Target, input []byte, stop func() bool, interesting func([]byte) bool) []byte {
+	object, ok := newSSZObject(target.Fork, target.Object)
+	if !ok {
+		return input
+	}
+	if err := object.UnmarshalSSZ(input); err != nil {
+		fmt.Printf("Input is not a %s, skipping SSZ minimization: %v\n", target.Object, err)
+		return input
+	}
+
+	current := input
+	for shrunk := true; shrunk && !stop(); {
+		shrunk = false
+		var lists []sszList
+		findLists(reflect.ValueOf(object), "", sszTag{}, &lists)
+		for i := 0; i < len(lists) && !stop(); i++ {
+			list := lists[i]
+			if isParallelList(list.path) {
+				continue
+			}
+			// Lists holding a value per element lose the same elements
+			shrinking := []sszList{list}
+			for _, other := range lists {
+				if slices.Contains(sszParallelLists[list.path], other.path) && other.value.Len() == list.value.Len() {
+					shrinking = append(shrinking, other)
+				}
+			}
+
+			kept := false
+			ddmin(list.value.Len(), stop, func(start int, end int) bool {
+				var originals []reflect.Value
+				for _, l := range shrinking {
+					originals = append(originals, removeElements(l.value, start, end))
+				}
+				candidate, err := object.MarshalSSZ()
+				if err != nil || !interesting(candidate) {
+					for j, l := range shrinking {
+						l.value.Set(originals[j])
+					}
+					return false
+				}
+				current = candidate
+				kept = true
+				fmt.Printf("Reduced %s to %d elements (%d bytes)\n", list.path, list.value.Len(), len(current))
+				return true
+			})
+			if kept {
+				// Lists nested in this one were replaced, so collect them again. Lists
+				// before it are unchanged and keep their positions.
+				shrunk = true
+				lists = nil
+				findLists(reflect.ValueOf(object), "", sszTag{}, &lists)
+			}
+		}
+	}
+	return current
+}
+
+// removeElements removes the elements in [start, end) from a list and returns the
+// original list, which is left intact so it can be restored.
+func removeElements(list reflect.Value, start int, end int) reflect.Value {
+	original := list.Slice(0, list.Len())
+	reduced := reflect.AppendSlice(
+		reflect.MakeSlice(original.Type(), 0, original.Len()-(end-start)),
+		original.Slice(0, start))
+	list.Set(reflect.AppendSlice(reduced, original.Slice(end, original.Len())))
+	return original
+}
+
+// isParallelList returns true if a list is shrunk together with another list.
+func isParallelList(path string) bool {
+	for _, parallel := range sszParallelLists {
+		if slices.Contains(parallel, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// sszList is a variable-length list in a decoded object.
+type sszList struct {
+	path  string
+	value reflect.Value
+}
+
+// findLists collects the variable-length lists reachable from a value, in the order they
+// are reached, so a list comes before the lists nested in its elements.
+// Bitlists are skipped, since their length is tied to other fields.
+func findLists(v reflect.Value, path string, tag sszTag, lists *[]sszList) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			findLists(v.Elem(), path, tag, lists)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.IsExported() {
+				findLists(v.Field(i), joinPath(path, snakeCase(field.Name)), newSSZTag(field), lists)
+			}
+		}
+	case reflect.Array, reflect.Slice:
+		if v.Type() == bitlistType {
+			return
+		}
+		// Empty lists are kept, so every list keeps its position while others shrink
+		if _, fixed := tag.fixedLength(); v.Kind() == reflect.Slice && !fixed {
+			*lists = append(*lists, sszList{path: path, value: v})
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			findLists(v.Index(i), fmt.Sprintf("%s[%d]", path, i), tag.inner(), lists)
+		}
+	}
+}
+
+// joinPath appends a field name to a path.
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// ddmin removes chunks of items with delta debugging. The remove function tries to remove
+// the items in [start, end) and returns true if it kept the removal; the indices are
+// relative to the items left after earlier removals. Chunks are halved until single
+// items can't be removed, or until stop returns true.
+func ddmin(length int, stop func() bool, remove func(start int, end int) bool) {
+	chunks := 2
+	for length > 0 && !stop() {
+		chunkSize := (length + chunks - 1) / chunks
+		removed := false
+		for start := 0; start < length && !stop(); start += chunkSize {
+			end := min(start+chunkSize, length)
+			if remove(start, end) {
+				length -= end - start
+				chunks = max(chunks-1, 2)
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			if chunkSize == 1 {
+				return
+			}
+			chunks = min(chunks*2, length)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/electra"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+func TestDdmin(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+	tests := 0
+	ddmin(len(items), func() bool { return false }, func(start int, end int) bool {
+		tests++
+		candidate := append(slices.Clone(items[:start]), items[end:]...)
+		// The failure needs both items
+		if !slices.Contains(candidate, 13) || !slices.Contains(candidate, 42) {
+			return false
+		}
+		items = candidate
+		return true
+	})
+	if !slices.Equal(items, []int{13, 42}) {
+		t.Errorf("ddmin left %v, want [13 42]", items)
+	}
+	if tests > 100 {
+		t.Errorf("ddmin took %d tests", tests)
+	}
+
+	// Stopping early leaves the items which weren't tried
+	calls := 0
+	ddmin(10, func() bool { return calls >= 3 }, func(start int, end int) bool {
+		calls++
+		return false
+	})
+	if calls != 3 {
+		t.Errorf("ddmin tried %d removals after being stopped, want 3", calls)
+	}
+	ddmin(0, func() bool { return false }, func(start int, end int) bool {
+		t.Error("ddmin tried to remove from an empty list")
+		return false
+	})
+}
+
+func TestShrinkBytes(t *testing.T) {
+	input := testInput(256)
+	needle := []byte{input[100], input[101]}
+	minimized := shrinkBytes(input, func() bool { return false }, func(candidate []byte) bool {
+		return bytes.Contains(candidate, needle)
+	})
+	if !bytes.Equal(minimized, needle) {
+		t.Errorf("shrinkBytes = %x, want %x", minimized, needle)
+	}
+}
+
+func TestShrinkSSZParallelLists(t *testing.T) {
+	target := &Target{Name: "state", Fork: "electra", Object: "BeaconState"}
+	input := testStateWith(t, func(state *electra.BeaconState) {
+		for i := range state.Balances {
+			state.Balances[i] = phase0.Gwei(i)
+			state.InactivityScores[i] = uint64(i)
+		}
+	})
+
+	// The divergence needs validator 42 and its own balance
+	minimized := shrinkSSZ(target, input, func() bool { return false }, func(candidate []byte) bool {
+		var state electra.BeaconState
+		if err := state.UnmarshalSSZ(candidate); err != nil {
+			return false
+		}
+		for i, validator := range state.Validators {
+			if validator.ExitEpoch == 42 {
+				return i < len(state.Balances) && state.Balances[i] == 42
+			}
+		}
+		return false
+	})
+
+	var state electra.BeaconState
+	if err := state.UnmarshalSSZ(minimized); err != nil {
+		t.Fatalf("minimized input doesn't decode: %v", err)
+	}
+	if len(state.Validators) != 1 || state.Validators[0].ExitEpoch != 42 {
+		t.Fatalf("minimized to %d validators, want validator 42 alone", len(state.Validators))
+	}
+	if !slices.Equal(state.Balances, []phase0.Gwei{42}) || !slices.Equal(state.InactivityScores, []uint64{42}) ||
+		len(state.PreviousEpochParticipation) != 1 || len(state.CurrentEpochParticipation) != 1 {
+		t.Errorf("parallel lists are %v, %v, %d and %d elements, want validator 42's", state.Balances,
+			state.InactivityScores, len(state.PreviousEpochParticipation), len(state.CurrentEpochParticipation))
+	}
+}
+
+func TestShrinkSSZNestedLists(t *testing.T) {
+	target := &Target{Name: "block", Fork: "electra", Object: "SignedBeaconBlock"}
+	// The divergence needs a transaction holding byte 3, which is in the first transaction
+	minimized := shrinkSSZ(target, testBlock(t), func() bool { return false }, func(candidate []byte) bool {
+		var block electra.SignedBeaconBlock
+		if err := block.UnmarshalSSZ(candidate); err != nil {
+			return false
+		}
+		return slices.ContainsFunc(block.Message.Body.ExecutionPayload.Transactions,
+			func(transaction bellatrix.Transaction) bool { return bytes.Contains(transaction, []byte{3}) })
+	})
+
+	var block electra.SignedBeaconBlock
+	if err := block.UnmarshalSSZ(minimized); err != nil {
+		t.Fatalf("minimized input doesn't decode: %v", err)
+	}
+	transactions := block.Message.Body.ExecutionPayload.Transactions
+	if len(transactions) != 1 || !bytes.Equal(transactions[0], []byte{3}) {
+		t.Errorf("transactions minimized to %v, want [[3]]", transactions)
+	}
+	if len(block.Message.Body.Attestations) != 0 {
+		t.Errorf("attestations minimized to %d, want none", len(block.Message.Body.Attestations))
+	}
+}
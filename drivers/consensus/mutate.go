@@ -1,7 +1,10 @@
 package main
 
 import (
-	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
 	"math/rand"
 	"sort"
 )
@@ -17,14 +20,93 @@ const (
 	AddBefore
 	AddAfter
 	Delete
+
+	// Havoc strategies operate on the input as a whole
+	BitFlip
+	InterestingValue
+	Arithmetic
+	BlockDuplicate
+	BlockSwap
+	Splice
 )
 
+var mutationNames = []string{
+	Replace:          "replace",
+	AddBefore:        "add-before",
+	AddAfter:         "add-after",
+	Delete:           "delete",
+	BitFlip:          "bit-flip",
+	InterestingValue: "interesting-value",
+	Arithmetic:       "arithmetic",
+	BlockDuplicate:   "block-duplicate",
+	BlockSwap:        "block-swap",
+	Splice:           "splice",
+}
+
+func (m Mutation) String() string {
+	if int(m) < len(mutationNames) {
+		return mutationNames[m]
+	}
+	return fmt.Sprintf("Mutation(%d)", int(m))
+}
+
+// havocStrategies lists the strategies Havoc picks from.
+var havocStrategies = []Mutation{
+	BitFlip,
+	InterestingValue,
+	Arithmetic,
+	BlockDuplicate,
+	BlockSwap,
+	Splice,
+}
+
+const (
+	// havocMaxStack is the most strategies stacked on a single input.
+	havocMaxStack = 16
+	// havocMaxBlock is the largest block duplicated or swapped.
+	havocMaxBlock = 1024
+	// havocMaxDelta is the largest value added to or subtracted from a word.
+	havocMaxDelta = 35
+)
+
+// Little-endian words which are more likely to hit edge cases than random ones.
+var interestingWords = []uint64{
+	0,
+	1,
+	math.MaxUint8,
+	math.MaxUint16,
+	math.MaxUint32,
+	math.MaxInt64,
+	math.MaxUint64,
+}
+
+// Big-endian field moduli and group orders used by precompiles and BLS signatures.
+var interestingModuli = [][]byte{
+	// BN254 base field modulus
+	hexBytes("30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd47"),
+	// BN254 scalar field modulus
+	hexBytes("30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000001"),
+	// BLS12-381 base field modulus
+	hexBytes("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab"),
+	// BLS12-381 scalar field modulus
+	hexBytes("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001"),
+	// secp256k1 base field modulus
+	hexBytes("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f"),
+	// secp256k1 group order
+	hexBytes("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141"),
+}
+
 // mutationProbabilities defines the likelihood of each mutation as a value between 0 and 1.
-var mutationProbabilities = map[Mutation]float64{
-	Replace:   0.01,
-	AddBefore: 0.001,
-	AddAfter:  0.001,
-	Delete:    0.001,
+// This is a slice rather than a map so the probability map is always built in the same
+// order, which keeps the output for a given seed the same across runs and machines.
+var mutationProbabilities = []struct {
+	Mutation    Mutation
+	Probability float64
+}{
+	{Replace, 0.01},
+	{AddBefore, 0.001},
+	{AddAfter, 0.001},
+	{Delete, 0.001},
 }
 
 // probabilityMap is a sorted map to determine which mutation should occur based on probabilities.
@@ -41,13 +123,13 @@ func buildProbabilityMap() []struct {
 		Mutation              Mutation
 	}{}
 
-	for mutation, probability := range mutationProbabilities {
-		if probability > 0 {
-			cumulativeProbability += probability
+	for _, entry := range mutationProbabilities {
+		if entry.Probability > 0 {
+			cumulativeProbability += entry.Probability
 			probMap = append(probMap, struct {
 				CumulativeProbability float64
 				Mutation              Mutation
-			}{cumulativeProbability, mutation})
+			}{cumulativeProbability, entry.Mutation})
 		}
 	}
 	return probMap
@@ -64,34 +146,207 @@ func findMutation(randomNumber float64) (Mutation, bool) {
 	return 0, false
 }
 
+// totalProbability is the likelihood of a byte being mutated at all.
+var totalProbability = probabilityMap[len(probabilityMap)-1].CumulativeProbability
+
+// ByteMutator mutates inputs byte by byte into a buffer which is reused between calls.
+type ByteMutator struct {
+	buf []byte
+}
+
 // Mutate mutates the input bytes based on predefined probabilities and a random seed.
+// Rather than drawing a random number for every byte, it samples the distance to the
+// next mutated byte from a geometric distribution and copies the unchanged span in
+// bulk. Each byte is still mutated independently with the same probabilities. The
+// returned slice is only valid until the next call. Edits are recorded in the trace if
+// it is not nil.
+func (m *ByteMutator) Mutate(input []byte, seed int64, trace *Trace) []byte {
+	random := rand.New(rand.NewSource(seed))
+
+	// Never write into the buffer we are reading from
+	if sameArray(input, m.buf) {
+		m.buf = nil
+	}
+	// Leave some room for insertions so large inputs aren't copied twice
+	if size := len(input) + len(input)/64 + 64; cap(m.buf) < size {
+		m.buf = make([]byte, 0, size)
+	}
+	out := m.buf[:0]
+
+	position := 0
+	for {
+		// Copy the unchanged bytes before the next mutation
+		skip := nextMutation(random, len(input)-position)
+		out = append(out, input[position:position+skip]...)
+		position += skip
+		if position == len(input) {
+			break
+		}
+
+		b := input[position]
+		position++
+		mutation, _ := findMutation(random.Float64() * totalProbability)
+		switch mutation {
+		case AddBefore:
+			before := byte(random.Intn(256))
+			trace.add(mutation.String(), len(out), nil, []byte{before})
+			out = append(out, before, b)
+		case AddAfter:
+			after := byte(random.Intn(256))
+			trace.add(mutation.String(), len(out)+1, nil, []byte{after})
+			out = append(out, b, after)
+		case Replace:
+			replace := byte(random.Intn(256))
+			trace.add(mutation.String(), len(out), []byte{b}, []byte{replace})
+			out = append(out, replace)
+		case Delete:
+			// Skip the current byte (effectively deleting it)
+			trace.add(mutation.String(), len(out), []byte{b}, nil)
+		}
+	}
+
+	m.buf = out
+	return out
+}
+
+// nextMutation returns the number of bytes before the next mutated byte, capped at the
+// number of remaining bytes. This is geometrically distributed, which is the same as
+// drawing a random number for every byte until one is mutated.
+func nextMutation(random *rand.Rand, remaining int) int {
+	if totalProbability >= 1 {
+		return 0
+	}
+	// Use (0, 1] so the logarithm is finite
+	u := 1 - random.Float64()
+	skip := math.Floor(math.Log(u) / math.Log1p(-totalProbability))
+	if skip >= float64(remaining) {
+		return remaining
+	}
+	return int(skip)
+}
+
+// sameArray returns true if both slices end at the same backing array, which is the
+// case for any slice of a buffer returned by a ByteMutator.
+func sameArray(a []byte, b []byte) bool {
+	if cap(a) == 0 || cap(b) == 0 {
+		return false
+	}
+	return &a[:cap(a)][cap(a)-1] == &b[:cap(b)][cap(b)-1]
+}
+
+// Mutate mutates the input bytes into a new buffer; see ByteMutator.Mutate.
 func Mutate(input []byte, seed int64) []byte {
+	var mutator ByteMutator
+	return mutator.Mutate(input, seed, nil)
+}
+
+// Havoc applies a random stack of whole-input strategies. The other input should be a
+// second corpus entry of the same type and is used for splicing; it may be nil. Edits
+// are recorded in the trace if it is not nil.
+func Havoc(input []byte, other []byte, seed int64, trace *Trace) []byte {
 	random := rand.New(rand.NewSource(seed))
-	var out bytes.Buffer
-
-	for _, b := range input {
-		randomNumber := random.Float64()
-		mutation, ok := findMutation(randomNumber)
-		if ok {
-			switch mutation {
-			case AddBefore:
-				before := byte(random.Intn(256))
-				out.WriteByte(before)
-				out.WriteByte(b)
-			case AddAfter:
-				after := byte(random.Intn(256))
-				out.WriteByte(b)
-				out.WriteByte(after)
-			case Replace:
-				replace := byte(random.Intn(256))
-				out.WriteByte(replace)
-			case Delete:
-				// Skip the current byte (effectively deleting it)
-			}
+	out := append([]byte(nil), input...)
+
+	stack := 1 << random.Intn(int(math.Log2(havocMaxStack))+1)
+	for i := 0; i < stack; i++ {
+		out = havocOnce(random, out, other, trace)
+	}
+	return out
+}
+
+// havocOnce applies a single random strategy to the data.
+func havocOnce(random *rand.Rand, data []byte, other []byte, trace *Trace) []byte {
+	strategy := havocStrategies[random.Intn(len(havocStrategies))]
+	switch strategy {
+	case BitFlip:
+		if len(data) > 0 {
+			bit := random.Intn(len(data) * 8)
+			old := data[bit/8]
+			data[bit/8] ^= 1 << (bit % 8)
+			trace.add(strategy.String(), bit/8, []byte{old}, data[bit/8:bit/8+1])
+		}
+	case InterestingValue:
+		var value []byte
+		if random.Intn(2) == 0 {
+			var word [8]byte
+			binary.LittleEndian.PutUint64(word[:], interestingWords[random.Intn(len(interestingWords))])
+			value = word[:random.Intn(8)+1]
 		} else {
-			// No mutation, write the original byte
-			out.WriteByte(b)
+			value = interestingModuli[random.Intn(len(interestingModuli))]
+			if random.Intn(2) == 0 {
+				value = reversed(value)
+			}
+		}
+		overwrite(random, data, value, strategy, trace)
+	case Arithmetic:
+		if len(data) >= 8 {
+			offset := random.Intn(len(data) - 7)
+			old := append([]byte(nil), data[offset:offset+8]...)
+			word := binary.LittleEndian.Uint64(data[offset:])
+			delta := uint64(1 + random.Intn(havocMaxDelta))
+			if random.Intn(2) == 0 {
+				word += delta
+			} else {
+				word -= delta
+			}
+			binary.LittleEndian.PutUint64(data[offset:], word)
+			trace.add(strategy.String(), offset, old, data[offset:offset+8])
+		}
+	case BlockDuplicate:
+		if len(data) > 0 {
+			length := 1 + random.Intn(min(len(data), havocMaxBlock))
+			start := random.Intn(len(data) - length + 1)
+			at := random.Intn(len(data) + 1)
+			block := append([]byte(nil), data[start:start+length]...)
+			data = append(data[:at], append(block, data[at:]...)...)
+			trace.add(strategy.String(), at, nil, block)
 		}
+	case BlockSwap:
+		if len(data) >= 2 {
+			length := 1 + random.Intn(min(len(data)/2, havocMaxBlock))
+			first := random.Intn(len(data) - 2*length + 1)
+			second := first + length + random.Intn(len(data)-first-2*length+1)
+			block := append([]byte(nil), data[first:first+length]...)
+			copy(data[first:], data[second:second+length])
+			copy(data[second:], block)
+			trace.add(strategy.String(), first, block, data[first:first+length])
+			trace.add(strategy.String(), second, data[first:first+length], block)
+		}
+	case Splice:
+		// Keep the data up to a random point and take the rest from the other entry
+		if len(other) > 0 && len(data) > 0 {
+			at := random.Intn(min(len(data), len(other)))
+			trace.addSplice(strategy.String(), at, data[at:], other[at:])
+			data = append(data[:at], other[at:]...)
+		}
+	}
+	return data
+}
+
+// overwrite writes the value over the data at a random offset.
+func overwrite(random *rand.Rand, data []byte, value []byte, mutation Mutation, trace *Trace) {
+	if len(data) < len(value) {
+		return
+	}
+	offset := random.Intn(len(data) - len(value) + 1)
+	trace.add(mutation.String(), offset, data[offset:offset+len(value)], value)
+	copy(data[offset:], value)
+}
+
+// reversed returns a reversed copy of the bytes.
+func reversed(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i, b := range data {
+		out[len(data)-1-i] = b
+	}
+	return out
+}
+
+// hexBytes decodes a hex constant.
+func hexBytes(s string) []byte {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
 	}
-	return out.Bytes()
+	return data
 }
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/attestantio/go-eth2-client/spec/altair"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/electra"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/holiman/uint256"
+	bitfield "github.com/prysmaticlabs/go-bitfield"
+)
+
+// testInput returns a fixed input which is the same on every machine.
+func testInput(size int) []byte {
+	input := make([]byte, size)
+	for i := range input {
+		input[i] = byte(i*31 + i>>8)
+	}
+	return input
+}
+
+// testState returns a small electra BeaconState encoded as SSZ.
+func testState(t testing.TB) []byte {
+	state := &electra.BeaconState{
+		Fork:                         &phase0.Fork{},
+		LatestBlockHeader:            &phase0.BeaconBlockHeader{},
+		BlockRoots:                   make([]phase0.Root, 8192),
+		StateRoots:                   make([]phase0.Root, 8192),
+		ETH1Data:                     &phase0.ETH1Data{BlockHash: make([]byte, 32)},
+		RANDAOMixes:                  make([]phase0.Root, 65536),
+		Slashings:                    make([]phase0.Gwei, 8192),
+		JustificationBits:            make([]byte, 1),
+		PreviousJustifiedCheckpoint:  &phase0.Checkpoint{},
+		CurrentJustifiedCheckpoint:   &phase0.Checkpoint{},
+		FinalizedCheckpoint:          &phase0.Checkpoint{},
+		CurrentSyncCommittee:         &altair.SyncCommittee{Pubkeys: make([]phase0.BLSPubKey, 512)},
+		NextSyncCommittee:            &altair.SyncCommittee{Pubkeys: make([]phase0.BLSPubKey, 512)},
+		LatestExecutionPayloadHeader: &deneb.ExecutionPayloadHeader{BaseFeePerGas: uint256.NewInt(7)},
+	}
+	for i := 0; i < 64; i++ {
+		state.Validators = append(state.Validators, &phase0.Validator{
+			WithdrawalCredentials: make([]byte, 32),
+			EffectiveBalance:      32_000_000_000,
+			ExitEpoch:             phase0.Epoch(i),
+		})
+		state.Balances = append(state.Balances, 32_000_000_000)
+		state.InactivityScores = append(state.InactivityScores, 0)
+		state.PreviousEpochParticipation = append(state.PreviousEpochParticipation, 7)
+		state.CurrentEpochParticipation = append(state.CurrentEpochParticipation, 7)
+	}
+
+	data, err := state.MarshalSSZ()
+	if err != nil {
+		t.Fatalf("failed to encode state: %v", err)
+	}
+	return data
+}
+
+// testBlock returns a small electra SignedBeaconBlock with nested variable-size fields
+// encoded as SSZ.
+func testBlock(t testing.TB) []byte {
+	attestation := &electra.Attestation{
+		AggregationBits: bitfield.NewBitlist(10),
+		Data: &phase0.AttestationData{
+			Source: &phase0.Checkpoint{},
+			Target: &phase0.Checkpoint{},
+		},
+		CommitteeBits: bitfield.NewBitvector64(),
+	}
+	block := &electra.SignedBeaconBlock{Message: &electra.BeaconBlock{Body: &electra.BeaconBlockBody{
+		ETH1Data:      &phase0.ETH1Data{BlockHash: make([]byte, 32)},
+		Attestations:  []*electra.Attestation{attestation, attestation},
+		SyncAggregate: &altair.SyncAggregate{SyncCommitteeBits: bitfield.NewBitvector512()},
+		ExecutionPayload: &deneb.ExecutionPayload{
+			BaseFeePerGas: uint256.NewInt(7),
+			Transactions:  []bellatrix.Transaction{{1, 2, 3}, {4}},
+		},
+		ExecutionRequests: &electra.ExecutionRequests{},
+	}}}
+
+	data, err := block.MarshalSSZ()
+	if err != nil {
+		t.Fatalf("failed to encode block: %v", err)
+	}
+	return data
+}
+
+func hashHex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// The golden hashes below must never change for an existing seed. If a change to the
+// mutators is intended to produce different output, update them in the same commit.
+
+func TestMutateGolden(t *testing.T) {
+	input := testInput(4096)
+	golden := map[int64]string{
+		0:    "6ed2118919f57bf35d72163b8ec56eb8d617b7ddedab07409d1d656da87ded6a",
+		1:    "c568247aeb333c6f058d7b8fb4f9f10a6dac0d32af639b09b6747996ce6cfed4",
+		42:   "4a4396e200f1ca2a4bf1b18f8cf0dfa5b57541243e5d104a2ca19876f2512639",
+		1337: "7577d2e2b3888ea5bfce6d31af0a224883b413c053154156e3a26ef22363b5df",
+	}
+	for seed, want := range golden {
+		if got := hashHex(Mutate(input, seed)); got != want {
+			t.Errorf("Mutate(seed=%d) = %s, want %s", seed, got, want)
+		}
+	}
+}
+
+func TestHavocGolden(t *testing.T) {
+	input := testInput(4096)
+	other := testInput(2048)
+	golden := map[int64]string{
+		0:    "f75446a4dd84a4167e462121133445b5263626bf5ad11ac91973c2ddadd7fcbf",
+		1:    "ce136223d60f1e9569478da1422b55cd915cece2a151338cdad73ae7fd723870",
+		42:   "b3bb339022848d7c01626c0b5e0f302336487ee344e8461bc1a137fafaaa8ae2",
+		1337: "525b11153e6d3456d15ca2e67a6204ffd84c3c94c065a8aeac903c2fb3ffceb9",
+	}
+	for seed, want := range golden {
+		if got := hashHex(Havoc(input, other, seed, nil)); got != want {
+			t.Errorf("Havoc(seed=%d) = %s, want %s", seed, got, want)
+		}
+	}
+}
+
+func TestMutateSSZGolden(t *testing.T) {
+	input := testState(t)
+	golden := map[int64]string{
+		0:    "a07ee374dc776dbb8b051ff3a80fe945811f206447406d098983e3864f17897e",
+		1:    "c19a740575fd37c6aaea3ad5b746d5616cb482297a83bcd5bd102c1c03fa38e3",
+		42:   "6ec08e8f982b3f95905272a02668ce1881fb25c377454721b4a1e27ef02903e6",
+		1337: "ce944b3db8a3ca3896135a34f64c17d62044af824aa8398f883b6c15a8902e9f",
+	}
+	for seed, want := range golden {
+		output, err := MutateSSZ("electra", "BeaconState", input, seed)
+		if err != nil {
+			t.Fatalf("MutateSSZ(seed=%d) failed: %v", seed, err)
+		}
+		if got := hashHex(output); got != want {
+			t.Errorf("MutateSSZ(seed=%d) = %s, want %s", seed, got, want)
+		}
+	}
+}
+
+func TestMutateOffsetsGolden(t *testing.T) {
+	input := testState(t)
+	golden := map[int64]string{
+		0:    "a0b2206b4a6c3dcf11a12798213913bc9117df1a62feed017a50b8a43aade46d",
+		1:    "e43409e7db5d2484aa0185f6575c73612a577a2202823c4bd89df009d94af85c",
+		42:   "51aacd695e00ac8315418749fb1584004f72c05afdac6c45fe0caba2aa5e7e9f",
+		1337: "74c1d1b42f6d863bbb6c9ca62b9311932d91fd6c72bb812e63d570938b6911d1",
+	}
+	for seed, want := range golden {
+		output, err := MutateOffsets("electra", "BeaconState", input, seed, nil)
+		if err != nil {
+			t.Fatalf("MutateOffsets(seed=%d) failed: %v", seed, err)
+		}
+		if got := hashHex(output); got != want {
+			t.Errorf("MutateOffsets(seed=%d) = %s, want %s", seed, got, want)
+		}
+	}
+}
+
+func TestMutateDictionaryGolden(t *testing.T) {
+	input := testInput(4096)
+	golden := map[int64]string{
+		0:    "351a011ad36cb0cac6aa86b8861372743a8ed26e82962f4aa1dbdc8bc533c051",
+		1:    "8f5513559edfc5e12996c77985efe13492e10b52020a3bf592d2b8959a024722",
+		42:   "0d1c26be7e7696497445b9d1668ee7c9b2d7fcb48fef5ab70cc738f594d21899",
+		1337: "31be2124fd62cbd75dd87e69941c81382508e734f3685d8bb84a9cc540f9f318",
+	}
+	for seed, want := range golden {
+		if got := hashHex(MutateDictionary(input, consensusDictionary, seed, nil)); got != want {
+			t.Errorf("MutateDictionary(seed=%d) = %s, want %s", seed, got, want)
+		}
+	}
+}
+
+func TestMutationPlanGolden(t *testing.T) {
+	// Havoc splices with corpus entries, so run without a corpus to not depend on local files
+	testChdir(t)
+	consensus, err := lookupTarget("sha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	execution, err := lookupTarget("bn256Add")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := testState(t)
+	call := testInput(256)
+
+	// Stacks produce the same inputs as mutateInput did before plans
+	stacked := []struct {
+		target   *Target
+		input    []byte
+		mutators string
+		want     string
+	}{
+		{consensus, state, "bytes", "b53764db6d7b5b48df5c052563b2971863d632546d31bb332ef8c082071b0e07"},
+		{consensus, state, "ssz", "fcae87b92f95395220c5c6f0f6f0cd1add66ea2b42eadc79bf10858783ec7767"},
+		{consensus, state, "offsets", "4b612685060b903c29ab32f0daab9f19bf7413c3814593710f8e28bda91534f0"},
+		{consensus, state, "dict", "8589abcb28fcfd05d01c5190678711a8613372bee96f111f7c1fdb8571e31cef"},
+		{consensus, state, "havoc", "7da566b682ffd1aece845d23c7d59ff6fbeeaca66153c6228bdb6aef3c5c7c21"},
+		{consensus, state, "ssz,offsets,dict,havoc,bytes", "1267388109bfcd94bba48f7a5eccbc2d87cf15c4af528657cf0760512b859046"},
+		{execution, call, "bytes", "3d17abea46c9acb468f4d640e0257e8698bde4aeec2790576d0f36b1ea426a83"},
+		{execution, call, "dict", "ca9da6df6e74a91dc631e3e54aa64f2fa92105d27e8359efbaace84be53da4de"},
+		{execution, call, "bytes,havoc", "0b04ae1fbc3b65ddf304784bb6044583289f6383a8a219261d46565b86dad720"},
+	}
+	for _, test := range stacked {
+		plan, err := NewStackedPlan(test.target, strings.Split(test.mutators, ","))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hashHex(plan.Mutate(test.input, 1, nil)); got != test.want {
+			t.Errorf("%s: stacked plan %s = %s, want %s", test.target.Name, test.mutators, got, test.want)
+		}
+	}
+
+	weighted := []struct {
+		target *Target
+		input  []byte
+		seed   int64
+		want   string
+	}{
+		{consensus, state, 0, "a6fec28edd7a4a5c2ebd47eda9c42817733f10df3c8dc358c3d8a3987720b747"},
+		{consensus, state, 1, "f6be1081babaf95116e969a84354ba958cc240dcf07a47c4b0993565978822be"},
+		{consensus, state, 2, "253b9ee91d06999f79bb470f3cdf882c8a51d51c0be73497e495f5b1e713fe35"},
+		{consensus, state, 3, "2ceaa9ae8bb1e9a69870c4b9bd4ac2ce871ffa3cebab80539011b4908ba1f47b"},
+		{execution, call, 0, "a56151173bbcd47723b14fd85e8dc5d12859333f9aa128d2eb66c4db11d70896"},
+		{execution, call, 1, "5dbf8cc9a71f5db6054e88e8bfd294c6a33d5c023a1d5452ade16ea28cc28679"},
+		{execution, call, 2, "c8a4482e0efac8fef54d87c5c3be82415e25946e946ef855b43d25cffbcddc82"},
+		{execution, call, 3, "1458f8a808a5fff2c1c6067282081fede4a61d62933f22fe8467ff263810f8a0"},
+	}
+	for _, test := range weighted {
+		plan, err := NewWeightedPlan(test.target, test.target.Mutators)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hashHex(plan.Mutate(test.input, test.seed, nil)); got != test.want {
+			t.Errorf("%s: weighted plan (seed=%d) = %s, want %s", test.target.Name, test.seed, got, test.want)
+		}
+	}
+}
+
+func TestTraceReplaysMutation(t *testing.T) {
+	target := &Target{Name: "test", Fork: "electra", Object: "SignedBeaconBlock", Dictionary: consensusDictionary}
+	input := testBlock(t)
+	for _, mutators := range []string{"bytes", "ssz", "offsets", "dict", "havoc", "ssz,offsets,dict,havoc,bytes"} {
+		for seed := int64(0); seed < 20; seed++ {
+			// Keep every span so the edits can be applied
+			trace := &Trace{MaxSpan: math.MaxInt}
+			plan, err := NewStackedPlan(target, strings.Split(mutators, ","))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := hashHex(plan.Mutate(input, seed, trace))
+
+			data := append([]byte(nil), input...)
+			for i, edit := range trace.Edits {
+				if edit.Offset+len(edit.Old) > len(data) || !bytes.Equal(data[edit.Offset:edit.Offset+len(edit.Old)], edit.Old) {
+					t.Fatalf("%s (seed=%d): edit %d does not match the data", mutators, seed, i)
+				}
+				data = append(data[:edit.Offset:edit.Offset], append(edit.New, data[edit.Offset+len(edit.Old):]...)...)
+			}
+			if got := hashHex(data); got != want {
+				t.Errorf("%s (seed=%d): replayed trace = %s, want %s", mutators, seed, got, want)
+			}
+		}
+	}
+}
+
+func TestTraceCompactsLongSpans(t *testing.T) {
+	state := testInput(4096)
+	other := testInput(2048)
+	trace := &Trace{Source: "corpus/electra/BeaconState/other"}
+	trace.add("replace", 5, state[5:6], []byte{0xff})
+	trace.add("fields", 100, state[100:200], other[100:101])
+	trace.addSplice("splice", 1000, state[1000:], other[1000:])
+
+	replace := trace.Edits[0]
+	if !bytes.Equal(replace.Old, state[5:6]) || !bytes.Equal(replace.New, []byte{0xff}) ||
+		replace.OldSpan != nil || replace.NewSpan != nil {
+		t.Errorf("short edit = %+v, want its bytes", replace)
+	}
+	fields := trace.Edits[1]
+	hash := sha256.Sum256(state[100:200])
+	if fields.Old != nil || fields.OldSpan == nil || fields.OldSpan.Length != 100 ||
+		!bytes.Equal(fields.OldSpan.SHA256, hash[:]) || !bytes.Equal(fields.New, other[100:101]) {
+		t.Errorf("long edit = %+v, want the length and hash of the old bytes", fields)
+	}
+	splice := trace.Edits[2]
+	source := traceSource{Path: "corpus/electra/BeaconState/other", Offset: 1000, Length: 1048}
+	if splice.New != nil || splice.NewSpan != nil || splice.Source == nil || *splice.Source != source ||
+		splice.OldSpan == nil || splice.OldSpan.Length != 3096 {
+		t.Errorf("splice = %+v, want the old length and source %+v", splice, source)
+	}
+
+	encoded, err := json.Marshal(trace.Edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encoded) > 1024 {
+		t.Errorf("encoded trace is %d bytes: %s", len(encoded), encoded)
+	}
+}
+
+func TestMutateDoesNotModifyInput(t *testing.T) {
+	input := testInput(4096)
+	want := hashHex(input)
+	for seed := int64(0); seed < 100; seed++ {
+		Mutate(input, seed)
+		Havoc(input, input, seed, nil)
+	}
+	if got := hashHex(input); got != want {
+		t.Errorf("input was modified")
+	}
+}
+
+func TestMutateDistribution(t *testing.T) {
+	// Insertions and deletions change the length, so the average length change tells us
+	// whether bytes are still mutated with the expected probabilities
+	const size = 1 << 20
+	input := make([]byte, size)
+	var mutator ByteMutator
+	var growth float64
+	const seeds = 20
+	for seed := int64(0); seed < seeds; seed++ {
+		growth += float64(len(mutator.Mutate(input, seed, nil)) - size)
+	}
+	growth /= seeds
+
+	var expected, variance float64
+	for _, entry := range mutationProbabilities {
+		switch entry.Mutation {
+		case AddBefore, AddAfter:
+			expected += size * entry.Probability
+			variance += size * entry.Probability
+		case Delete:
+			expected -= size * entry.Probability
+			variance += size * entry.Probability
+		}
+	}
+	// Allow five standard deviations of the mean
+	if tolerance := 5 * math.Sqrt(variance/seeds); math.Abs(growth-expected) > tolerance {
+		t.Errorf("average growth %.1f bytes, want %.1f ± %.1f", growth, expected, tolerance)
+	}
+}
+
+func TestByteMutatorReusesBuffer(t *testing.T) {
+	input := testInput(4096)
+	var mutator ByteMutator
+	first := hashHex(mutator.Mutate(input, 1, nil))
+	// Mutating a previous output must not corrupt it while it is being read
+	mutator.Mutate(mutator.Mutate(input, 2, nil), 3, nil)
+	if got := hashHex(mutator.Mutate(input, 1, nil)); got != first {
+		t.Errorf("Mutate(seed=1) = %s after reuse, want %s", got, first)
+	}
+}
+
+// benchmarkState returns a real BeaconState from the corpus, which is populated by
+// running the driver once.
+func benchmarkState(b *testing.B) []byte {
+	dir := filepath.Join("corpus", "electra", "BeaconState")
+	files, err := listFiles(dir)
+	if err != nil || len(files) == 0 {
+		b.Skipf("no BeaconState in %s, run the driver to populate the corpus", dir)
+	}
+	// Use the largest state available
+	var state []byte
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			b.Fatalf("failed to read state: %v", err)
+		}
+		if len(data) > len(state) {
+			state = data
+		}
+	}
+	return state
+}
+
+func BenchmarkMutateState(b *testing.B) {
+	state := benchmarkState(b)
+	var mutator ByteMutator
+	b.SetBytes(int64(len(state)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mutator.Mutate(state, int64(i), nil)
+	}
+}
+
+func BenchmarkMutateSyntheticState(b *testing.B) {
+	state := testState(b)
+	var mutator ByteMutator
+	b.SetBytes(int64(len(state)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mutator.Mutate(state, int64(i), nil)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Mutator turns a corpus entry into a new input. Implementations must draw all their
+// randomness from the given source so mutation stays reproducible. The source is shared
+// with the mutators stacked after it, so drawing more values changes their inputs too.
+// The returned slice may be a reused buffer and must not be the input itself.
+type Mutator interface {
+	Name() string
+	Mutate(random *rand.Rand, input []byte) []byte
+}
+
+// TracingMutator is implemented by mutators which record their own edits. Edits of
+// other mutators are recorded as the span of bytes which changed.
+type TracingMutator interface {
+	Mutator
+	MutateTraced(random *rand.Rand, input []byte, trace *Trace) []byte
+}
+
+// MutatorFactory creates a mutator for a target.
+type MutatorFactory func(target *Target) Mutator
+
+// mutatorRegistry maps mutator names to their factories.
+var mutatorRegistry = map[string]MutatorFactory{}
+
+// RegisterMutator makes a mutator available by name. It is meant to be called from init
+// functions, so registering a name twice panics.
+func RegisterMutator(name string, factory MutatorFactory) {
+	if _, exists := mutatorRegistry[name]; exists {
+		panic(fmt.Sprintf("mutator %s registered twice", name))
+	}
+	mutatorRegistry[name] = factory
+}
+
+// NewMutator creates the named mutator for a target.
+func NewMutator(name string, target *Target) (Mutator, error) {
+	factory, ok := mutatorRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown mutator %q (known: %v)", name, mutatorNames())
+	}
+	return factory(target), nil
+}
+
+// mutatorNames returns the sorted names of registered mutators.
+func mutatorNames() []string {
+	var names []string
+	for name := range mutatorRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MutatorWeight is how often a mutator is picked relative to the others.
+type MutatorWeight struct {
+	Name   string
+	Weight float64
+}
+
+// ParseMutatorWeights parses a comma-separated list of name=weight pairs.
+func ParseMutatorWeights(text string) ([]MutatorWeight, error) {
+	var weights []MutatorWeight
+	for _, entry := range strings.Split(text, ",") {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("mutator weight %q is not name=weight", entry)
+		}
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight for mutator %s: %q", name, value)
+		}
+		weights = append(weights, MutatorWeight{Name: name, Weight: weight})
+	}
+	return weights, nil
+}
+
+// MutationPlan decides which mutators run on each corpus entry. Either every mutator
+// in the stack is applied in order, or a single one is picked by weight.
+type MutationPlan struct {
+	stack    []Mutator
+	weighted []Mutator
+	weights  []float64 // Cumulative, in the same order as weighted
+}
+
+// NewStackedPlan creates a plan which applies the named mutators in order.
+func NewStackedPlan(target *Target, names []string) (*MutationPlan, error) {
+	plan := &MutationPlan{}
+	for _, name := range names {
+		mutator, err := NewMutator(name, target)
+		if err != nil {
+			return nil, err
+		}
+		plan.stack = append(plan.stack, mutator)
+	}
+	return plan, nil
+}
+
+// NewWeightedPlan creates a plan which picks one mutator per input by weight.
+func NewWeightedPlan(target *Target, weights []MutatorWeight) (*MutationPlan, error) {
+	plan := &MutationPlan{}
+	var total float64
+	for _, entry := range weights {
+		if entry.Weight == 0 {
+			continue
+		}
+		mutator, err := NewMutator(entry.Name, target)
+		if err != nil {
+			return nil, err
+		}
+		total += entry.Weight
+		plan.weighted = append(plan.weighted, mutator)
+		plan.weights = append(plan.weights, total)
+	}
+	if len(plan.weighted) == 0 {
+		return nil, fmt.Errorf("target %s has no mutators with a weight", target.Name)
+	}
+	return plan, nil
+}
+
+// String describes the plan in the format of -mutator for stacks and -weights otherwise.
+func (p *MutationPlan) String() string {
+	var entries []string
+	for _, mutator := range p.stack {
+		entries = append(entries, mutator.Name())
+	}
+	previous := 0.0
+	for i, mutator := range p.weighted {
+		entries = append(entries, fmt.Sprintf("%s=%g", mutator.Name(), p.weights[i]-previous))
+		previous = p.weights[i]
+	}
+	return strings.Join(entries, ",")
+}
+
+// Mutate applies the plan to a corpus entry. The result may refer to a reused buffer
+// and is only valid until the next call. Edits are recorded in the trace if it is not nil.
+func (p *MutationPlan) Mutate(entry []byte, seed int64, trace *Trace) []byte {
+	random := rand.New(rand.NewSource(seed))
+
+	mutators := p.stack
+	if len(p.weighted) > 0 {
+		total := p.weights[len(p.weights)-1]
+		pick := random.Float64() * total
+		index := sort.Search(len(p.weights), func(i int) bool { return p.weights[i] > pick })
+		mutators = p.weighted[min(index, len(p.weighted)-1):][:1]
+	}
+
+	mutated := entry
+	for _, mutator := range mutators {
+		// Mutators draw from the plan's source in order, so a stack produces the same
+		// inputs for a seed as before there were plans
+		trace.setMutator(mutator.Name())
+		if tracing, ok := mutator.(TracingMutator); ok && trace != nil {
+			mutated = tracing.MutateTraced(random, mutated, trace)
+		} else {
+			output := mutator.Mutate(random, mutated)
+			trace.addDiff("changed", mutated, output)
+			mutated = output
+		}
+	}
+	return mutated
+}
+
+func init() {
+	RegisterMutator("bytes", func(target *Target) Mutator { return &bytesMutator{} })
+	RegisterMutator("ssz", func(target *Target) Mutator { return &sszMutator{target: target} })
+	RegisterMutator("offsets", func(target *Target) Mutator { return &offsetsMutator{target: target} })
+	RegisterMutator("dict", func(target *Target) Mutator { return &dictMutator{target: target} })
+	RegisterMutator("havoc", func(target *Target) Mutator { return &havocMutator{target: target} })
+}
+
+// bytesMutator mutates raw bytes with a ByteMutator.
+type bytesMutator struct {
+	bytes ByteMutator
+}
+
+func (m *bytesMutator) Name() string { return "bytes" }
+
+func (m *bytesMutator) Mutate(random *rand.Rand, input []byte) []byte {
+	return m.MutateTraced(random, input, nil)
+}
+
+func (m *bytesMutator) MutateTraced(random *rand.Rand, input []byte, trace *Trace) []byte {
+	return m.bytes.Mutate(input, random.Int63(), trace)
+}
+
+// sszMutator mutates decoded fields, falling back to raw bytes if the entry isn't a
+// known SSZ object.
+type sszMutator struct {
+	target *Target
+	bytes  ByteMutator
+}
+
+func (m *sszMutator) Name() string { return "ssz" }
+
+func (m *sszMutator) Mutate(random *rand.Rand, input []byte) []byte {
+	return m.MutateTraced(random, input, nil)
+}
+
+func (m *sszMutator) MutateTraced(random *rand.Rand, input []byte, trace *Trace) []byte {
+	seed := random.Int63()
+	output, err := MutateSSZ(m.target.Fork, m.target.Object, input, seed)
+	if err != nil {
+		// Inputs which an earlier mutation left undecodable are common, so fall back silently
+		return m.bytes.Mutate(input, seed, trace)
+	}
+	// Fields are changed on the decoded object, so only the changed span is known
+	trace.addDiff("fields", input, output)
+	return output
+}
+
+// offsetsMutator mutates SSZ offsets, falling back to raw bytes if the entry has no
+// offsets to mutate.
+type offsetsMutator struct {
+	target *Target
+	bytes  ByteMutator
+}
+
+func (m *offsetsMutator) Name() string { return "offsets" }
+
+func (m *offsetsMutator) Mutate(random *rand.Rand, input []byte) []byte {
+	return m.MutateTraced(random, input, nil)
+}
+
+func (m *offsetsMutator) MutateTraced(random *rand.Rand, input []byte, trace *Trace) []byte {
+	seed := random.Int63()
+	output, err := MutateOffsets(m.target.Fork, m.target.Object, input, seed, trace)
+	if err != nil {
+		if err != errNotSSZ && err != errNoOffsets {
+			fmt.Printf("Error mutating offsets: %v\n", err)
+		}
+		return m.bytes.Mutate(input, seed, trace)
+	}
+	return output
+}
+
+// dictMutator writes the target's dictionary tokens, falling back to raw bytes if the
+// target has none.
+type dictMutator struct {
+	target *Target
+	bytes  ByteMutator
+}
+
+func (m *dictMutator) Name() string { return "dict" }
+
+func (m *dictMutator) Mutate(random *rand.Rand, input []byte) []byte {
+	return m.MutateTraced(random, input, nil)
+}
+
+func (m *dictMutator) MutateTraced(random *rand.Rand, input []byte, trace *Trace) []byte {
+	seed := random.Int63()
+	if len(m.target.Dictionary) == 0 {
+		return m.bytes.Mutate(input, seed, trace)
+	}
+	return MutateDictionary(input, m.target.Dictionary, seed, trace)
+}
+
+// havocMutator applies whole-input strategies, splicing with another entry of the
+// same type.
+type havocMutator struct {
+	target *Target
+}
+
+func (m *havocMutator) Name() string { return "havoc" }
+
+func (m *havocMutator) Mutate(random *rand.Rand, input []byte) []byte {
+	return m.MutateTraced(random, input, nil)
+}
+
+func (m *havocMutator) MutateTraced(random *rand.Rand, input []byte, trace *Trace) []byte {
+	seed := random.Int63()
+	otherSeed := random.Int63()
+	other, err := Get(m.target.Fork, m.target.Object, otherSeed)
+	if err != nil {
+		other = nil
+	} else if trace != nil {
+		// Splices are traced by where they copy from rather than the copied bytes
+		path, _ := CorpusPath(m.target.Fork, m.target.Object, otherSeed)
+		trace.setSource(path)
+	}
+	return Havoc(input, other, seed, trace)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// copyResults copies outputs out of the clients' shared memory, which the next input
+// overwrites.
+func copyResults(results map[string]Result) map[string]Result {
+	copied := make(map[string]Result)
+	for clientName, result := range results {
+		result.Output = append([]byte(nil), result.Output...)
+		copied[clientName] = result
+	}
+	return copied
+}
+
+// nondeterminism is a finding for a client whose result changed when an input was sent
+// again, with a vote holding both of its results and the results to save.
+type nondeterminism struct {
+	finding *Finding
+	runs    *Vote
+	results map[string]Result // The first result, and the second as the client's rerun
+}
+
+// checkDeterminism sends an input to the clients again and returns a finding for every
+// client whose status or output bytes differ from its first result, the clients which
+// crashed the second time and those which timed out. First must hold copies of the
+// first results.
+func checkDeterminism(server *Server, target *Target, seed int64, input []byte,
+	first map[string]Result) ([]*nondeterminism, []*Client, []*Client) {
+	versions := server.Versions()
+	results, crashed, timedOut := server.Process(input)
+	results = copyResults(results)
+
+	var clientNames []string
+	for clientName := range first {
+		clientNames = append(clientNames, clientName)
+	}
+	sort.Strings(clientNames)
+
+	var found []*nondeterminism
+	for _, clientName := range clientNames {
+		result, ok := results[clientName]
+		if !ok || !resultChanged(first[clientName], result) {
+			continue
+		}
+		found = append(found, newNondeterminism(
+			target, seed, input, clientName, versions[clientName], first[clientName], result))
+	}
+	return found, crashed, timedOut
+}
+
+// sendTwice sends an input to a single client twice and returns a copy of its first result
+// and its second, or the error of the run which returned no result.
+func sendTwice(server *Server, clientName string, input []byte) (Result, Result, error) {
+	first, err := server.ProcessClient(clientName, input)
+	if err != nil {
+		return Result{}, Result{}, err
+	}
+	first.Output = append([]byte(nil), first.Output...)
+	second, err := server.ProcessClient(clientName, input)
+	if err != nil {
+		return first, Result{}, err
+	}
+	return first, second, nil
+}
+
+// resultChanged returns true if a client's second result for an input differs from its
+// first in status or output bytes. A client's own results must be identical, however the
+// target compares clients.
+func resultChanged(first Result, second Result) bool {
+	return first.Err != second.Err || !bytes.Equal(first.Output, second.Output)
+}
+
+// newNondeterminism describes a client whose result for an input changed when the input
+// was sent again. The second result is saved as the client's rerun output.
+func newNondeterminism(target *Target, seed int64, input []byte, clientName string, version string,
+	first Result, second Result) *nondeterminism {
+	runs := &Vote{Groups: []ResultGroup{{Clients: []string{clientName}, Result: first}}}
+	results := map[string]Result{clientName: first}
+	finding := NewFinding(target, seed, input, runs, results, map[string]string{clientName: version})
+	finding.Nondeterministic = clientName
+	finding.Clients[0].Duration = first.Duration.String()
+
+	rerunName := clientName + ".rerun"
+	outputHash := sha256.Sum256(second.Output)
+	finding.Rerun = &ClientResult{
+		Name:       clientName,
+		Version:    version,
+		Status:     resultStatus(second),
+		Group:      1,
+		Output:     rerunName + ".out",
+		OutputHash: hex.EncodeToString(outputHash[:]),
+		Duration:   second.Duration.String(),
+	}
+
+	// Diff the two runs like two clients
+	runs.Groups = append(runs.Groups, ResultGroup{Clients: []string{rerunName}, Result: second})
+	results[rerunName] = second
+	finding.Diff, finding.Fields = DiffOutputs(target, runs)
+	finding.Signature = NondeterminismSignature(target, finding)
+	return &nondeterminism{finding: finding, runs: runs, results: results}
+}
+
+// NondeterminismSignature describes a nondeterministic client by its target, its
+// statuses in both runs and the top-level fields which differ.
+func NondeterminismSignature(target *Target, finding *Finding) string {
+	parts := []string{
+		"target=" + target.Name,
+		"nondeterministic=" + finding.Nondeterministic,
+		fmt.Sprintf("statuses=%s,%s", finding.Clients[0].Status, finding.Rerun.Status),
+	}
+	if len(finding.Fields) > 0 {
+		parts = append(parts, "fields="+strings.Join(finding.Fields, ","))
+	}
+	return strings.Join(parts, "; ")
+}
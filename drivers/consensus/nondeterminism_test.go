@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResultChanged(t *testing.T) {
+	tests := []struct {
+		first  Result
+		second Result
+		want   bool
+	}{
+		{Result{Output: []byte{1}}, Result{Output: []byte{1}, Duration: time.Second}, false},
+		{Result{Output: []byte{1}}, Result{Output: []byte{2}}, true},
+		{Result{Output: []byte{1}}, Result{Output: []byte{1}, Err: true}, true},
+		// Equivalent for the error-class comparator, but not the same message
+		{Result{Output: []byte("invalid input length"), Err: true},
+			Result{Output: []byte("invalid length"), Err: true}, true},
+		{Result{}, Result{Output: []byte{}}, false},
+	}
+	for _, test := range tests {
+		if got := resultChanged(test.first, test.second); got != test.want {
+			t.Errorf("resultChanged(%v, %v) = %v, want %v", test.first, test.second, got, test.want)
+		}
+	}
+}
+
+func TestNewNondeterminism(t *testing.T) {
+	target := &Target{Name: "bn256Add", Comparator: errorClassComparator{}}
+	first := Result{Output: []byte{1}, Duration: time.Millisecond}
+	second := Result{Output: []byte("invalid input length"), Err: true, Duration: 2 * time.Millisecond}
+
+	found := newNondeterminism(target, 3, testInput(64), "golang", "geth-1.15.5", first, second)
+	finding := found.finding
+	if want := "target=bn256Add; nondeterministic=golang; statuses=accepted,rejected"; finding.Signature != want {
+		t.Errorf("signature = %q, want %q", finding.Signature, want)
+	}
+	if len(finding.Clients) != 1 || finding.Clients[0].Name != "golang" || finding.Clients[0].Version != "geth-1.15.5" {
+		t.Errorf("clients = %+v, want golang alone", finding.Clients)
+	}
+	if rerun := finding.Rerun; rerun.Status != "rejected" || rerun.Output != "golang.rerun.out" || rerun.Group != 1 {
+		t.Errorf("rerun = %+v", rerun)
+	}
+	if string(found.results["golang.rerun"].Output) != string(second.Output) || len(found.results) != 2 {
+		t.Errorf("results to save = %v", found.results)
+	}
+}
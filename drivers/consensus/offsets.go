@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+)
+
+// sszOffsetSize is the size of an offset to a variable-size field or list element.
+const sszOffsetSize = 4
+
+// errNoOffsets is returned when the object has no variable-size fields to mutate.
+var errNoOffsets = errors.New("object has no offsets")
+
+// offsetTable is the offsets of a variable-size container or of a list of variable-size
+// elements, as found in an encoded object. Positions are absolute, offset values are
+// relative to the start of the container.
+type offsetTable struct {
+	start     int   // Start of the container
+	end       int   // End of the container
+	fixedSize int   // Expected value of the first offset
+	positions []int // Where each offset is encoded
+	parent    int   // Index of the enclosing table, -1 for the object itself
+	section   int   // Which of the parent's sections the container is in
+}
+
+// value returns the offset at index i.
+func (t *offsetTable) value(data []byte, i int) int {
+	return int(binary.LittleEndian.Uint32(data[t.positions[i]:]))
+}
+
+// setValue overwrites the offset at index i and records the edit in the trace.
+func (t *offsetTable) setValue(data []byte, i int, value int, kind string, trace *Trace) {
+	position := t.positions[i]
+	old := binary.LittleEndian.Uint32(data[position:])
+	binary.LittleEndian.PutUint32(data[position:], uint32(value))
+	if old != uint32(value) {
+		trace.add(kind, position, binary.LittleEndian.AppendUint32(nil, old), data[position:position+sszOffsetSize])
+	}
+}
+
+// sectionBounds returns the absolute start and end of variable section i.
+func (t *offsetTable) sectionBounds(data []byte, i int) (int, int) {
+	start := t.start + t.value(data, i)
+	if i+1 < len(t.positions) {
+		return start, t.start + t.value(data, i+1)
+	}
+	return start, t.end
+}
+
+// MutateOffsets mutates the offsets of the variable-size fields and list elements in
+// an encoded object, which is where SSZ decoders disagree most often. The offset
+// tables are found by walking the fork's type for the object, so only inputs which
+// are valid SSZ can be mutated. Edits are recorded in the trace if it is not nil.
+func MutateOffsets(fork string, object string, input []byte, seed int64, trace *Trace) ([]byte, error) {
+	value, ok := newSSZObject(fork, object)
+	if !ok {
+		return nil, errNotSSZ
+	}
+	typ := reflect.TypeOf(value).Elem()
+	if _, fixed := sszSize(typ, sszTag{}); fixed {
+		return nil, errNoOffsets
+	}
+
+	var tables []offsetTable
+	if err := parseOffsets(input, 0, len(input), typ, sszTag{}, -1, 0, &tables); err != nil {
+		return nil, fmt.Errorf("failed to parse %s.%s offsets: %w", fork, object, err)
+	}
+
+	random := rand.New(rand.NewSource(seed))
+	output := append([]byte(nil), input...)
+	mutations := 1 + random.Intn(3)
+	for i := 0; i < mutations; i++ {
+		if len(tables) == 0 {
+			break
+		}
+		output = mutateOffsetTable(random, output, tables, random.Intn(len(tables)), trace)
+
+		// Keep going only while the offsets are still consistent
+		tables = tables[:0]
+		if parseOffsets(output, 0, len(output), typ, sszTag{}, -1, 0, &tables) != nil {
+			break
+		}
+	}
+	return output, nil
+}
+
+// sszSize returns the encoded size of a type, or false if it is variable-size.
+func sszSize(typ reflect.Type, tag sszTag) (int, bool) {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return sszSize(typ.Elem(), tag)
+
+	case reflect.Bool, reflect.Uint8:
+		return 1, true
+	case reflect.Uint16:
+		return 2, true
+	case reflect.Uint32:
+		return 4, true
+	case reflect.Uint64:
+		return 8, true
+
+	case reflect.Array:
+		size, fixed := sszSize(typ.Elem(), tag.inner())
+		return size * typ.Len(), fixed
+
+	case reflect.Slice:
+		if typ == bitvector4Type {
+			return 1, true
+		}
+		length, ok := tag.fixedLength()
+		if !ok || typ == bitlistType {
+			return 0, false
+		}
+		size, fixed := sszSize(typ.Elem(), tag.inner())
+		return size * length, fixed
+
+	case reflect.Struct:
+		total := 0
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			size, fixed := sszSize(field.Type, newSSZTag(field))
+			if !fixed {
+				return 0, false
+			}
+			total += size
+		}
+		return total, true
+	}
+	return 0, false
+}
+
+// parseOffsets records the offset tables of a variable-size value encoded in
+// data[start:end] and of every variable-size value nested in it.
+func parseOffsets(data []byte, start int, end int, typ reflect.Type, tag sszTag,
+	parent int, section int, tables *[]offsetTable) error {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	table := offsetTable{start: start, end: end, parent: parent, section: section}
+	var types []reflect.Type
+	var tags []sszTag
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldTag := newSSZTag(field)
+			size, fixed := sszSize(field.Type, fieldTag)
+			if !fixed {
+				table.positions = append(table.positions, start+table.fixedSize)
+				types = append(types, field.Type)
+				tags = append(tags, fieldTag)
+				size = sszOffsetSize
+			}
+			table.fixedSize += size
+		}
+
+	case reflect.Slice, reflect.Array:
+		if _, fixed := sszSize(typ.Elem(), tag.inner()); fixed || typ == bitlistType {
+			// Lists of fixed-size elements don't have offsets
+			return nil
+		}
+		if start == end {
+			return nil
+		}
+		if end-start < sszOffsetSize {
+			return fmt.Errorf("list at %d is too short for an offset", start)
+		}
+		first := int(binary.LittleEndian.Uint32(data[start:]))
+		if first%sszOffsetSize != 0 || first == 0 || first > end-start {
+			return fmt.Errorf("invalid first list offset %d at %d", first, start)
+		}
+		table.fixedSize = first
+		for i := 0; i < first/sszOffsetSize; i++ {
+			table.positions = append(table.positions, start+i*sszOffsetSize)
+			types = append(types, typ.Elem())
+			tags = append(tags, tag.inner())
+		}
+
+	default:
+		return nil
+	}
+
+	if start+table.fixedSize > end {
+		return fmt.Errorf("container at %d is shorter than its fixed size %d", start, table.fixedSize)
+	}
+	previous := table.fixedSize
+	for i := range table.positions {
+		value := table.value(data, i)
+		if (i == 0 && value != table.fixedSize) || value < previous || start+value > end {
+			return fmt.Errorf("invalid offset %d at %d", value, table.positions[i])
+		}
+		previous = value
+	}
+
+	if len(table.positions) == 0 {
+		return nil
+	}
+	*tables = append(*tables, table)
+	index := len(*tables) - 1
+	for i := range table.positions {
+		sectionStart, sectionEnd := table.sectionBounds(data, i)
+		if err := parseOffsets(data, sectionStart, sectionEnd, types[i], tags[i], index, i, tables); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mutateOffsetTable applies one mutation to the offsets or variable sections of a
+// table. It may return a different slice if sections were resized.
+func mutateOffsetTable(random *rand.Rand, data []byte, tables []offsetTable, index int, trace *Trace) []byte {
+	table := &tables[index]
+	count := len(table.positions)
+	i := random.Intn(count)
+	length := table.end - table.start
+
+	switch random.Intn(5) {
+	case 0:
+		// The first offset doesn't match the fixed size
+		deltas := []int{-table.fixedSize, -sszOffsetSize, -1, 1, sszOffsetSize}
+		table.setValue(data, 0, max(0, table.fixedSize+deltas[random.Intn(len(deltas))]), "first-offset", trace)
+	case 1:
+		// Offsets out of order
+		if count > 1 {
+			j := random.Intn(count)
+			a, b := table.value(data, i), table.value(data, j)
+			table.setValue(data, i, b, "out-of-order", trace)
+			table.setValue(data, j, a, "out-of-order", trace)
+		} else {
+			table.setValue(data, i, max(0, table.value(data, i)-1-random.Intn(sszOffsetSize)), "out-of-order", trace)
+		}
+	case 2:
+		// Overlap with an earlier section, or with the fixed part
+		if i > 0 {
+			j := random.Intn(i)
+			sectionStart, sectionEnd := table.sectionBounds(data, j)
+			table.setValue(data, i, sectionStart-table.start+random.Intn(sectionEnd-sectionStart+1), "overlap", trace)
+		} else {
+			table.setValue(data, i, random.Intn(table.fixedSize+1), "overlap", trace)
+		}
+	case 3:
+		// Past the end of the container
+		values := []int{length + 1, length + 1 + random.Intn(256), math.MaxUint32}
+		table.setValue(data, i, values[random.Intn(len(values))], "past-end", trace)
+	default:
+		return resizeSection(random, data, tables, index, i, trace)
+	}
+	return data
+}
+
+// resizeSection truncates or extends a variable section and shifts every offset which
+// points after it, so the offsets still match the data.
+func resizeSection(random *rand.Rand, data []byte, tables []offsetTable, index int, section int,
+	trace *Trace) []byte {
+	sectionStart, sectionEnd := tables[index].sectionBounds(data, section)
+	sectionLength := sectionEnd - sectionStart
+
+	var output []byte
+	var delta int
+	if sectionLength > 0 && random.Intn(2) == 0 {
+		delta = -(1 + random.Intn(sectionLength))
+		output = append(append(output, data[:sectionEnd+delta]...), data[sectionEnd:]...)
+		trace.add("truncate", sectionEnd+delta, data[sectionEnd+delta:sectionEnd], nil)
+	} else {
+		// Append copies of the section from its start, or zeros if it is empty
+		extra := make([]byte, 1+random.Intn(64))
+		if sectionLength > 0 {
+			for j := range extra {
+				extra[j] = data[sectionStart+j%sectionLength]
+			}
+		}
+		delta = len(extra)
+		output = append(append(append(output, data[:sectionEnd]...), extra...), data[sectionEnd:]...)
+		trace.add("extend", sectionEnd, nil, extra)
+	}
+
+	// Offsets are all before the section, so their positions don't move
+	for index >= 0 {
+		table := &tables[index]
+		for j := section + 1; j < len(table.positions); j++ {
+			table.setValue(output, j, table.value(output, j)+delta, "shift", trace)
+		}
+		index, section = table.parent, table.section
+	}
+	return output
+}
@@ -0,0 +1,586 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	blsfp "github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+	blsfr "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	bnfp "github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	bnfr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	gokzg4844 "github.com/crate-crypto/go-kzg-4844"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// argKind is how a precompile argument is encoded, which decides how it is mutated.
+type argKind int
+
+const (
+	argRaw         argKind = iota // Opaque bytes
+	argLength                     // Length word in a modexp header
+	argRecoveryID                 // The ecrecover v word
+	argSecpScalar                 // secp256k1 signature value
+	argBN254Scalar                // BN254 scalar word
+	argBN254G1                    // Uncompressed BN254 G1 point
+	argBN254G2                    // Uncompressed BN254 G2 point
+	argBLSScalar                  // BLS12-381 scalar word, also used for KZG field elements
+	argBLSFp                      // BLS12-381 base field element padded to 64 bytes
+	argBLSFp2                     // BLS12-381 extension field element
+	argBLSG1                      // Uncompressed BLS12-381 G1 point
+	argBLSG2                      // Uncompressed BLS12-381 G2 point
+	argKZGPoint                   // Compressed BLS12-381 G1 point
+)
+
+// precompileArg is a single encoded argument of a precompile input.
+type precompileArg struct {
+	kind  argKind
+	value []byte
+}
+
+const (
+	// validMaxMutations is the most arguments mutated in a generated input
+	validMaxMutations = 3
+
+	// kzgOpenings is how many KZG openings are computed for point evaluation inputs
+	kzgOpenings = 8
+)
+
+var (
+	bn254P = bnfp.Modulus()
+	bn254R = bnfr.Modulus()
+	blsP   = blsfp.Modulus()
+	blsR   = blsfr.Modulus()
+	secpN  = secp256k1.S256().Params().N
+)
+
+// extremeLengths are modexp lengths around gas and allocation limits.
+var extremeLengths = []uint64{0, 1, 31, 32, 33, 64, 1024, 1025, 1 << 16, 1 << 32, 1<<63 - 1, 1<<64 - 1}
+
+// recoveryIDs are ecrecover v values around the valid 27 and 28.
+var recoveryIDs = []uint64{0, 1, 26, 27, 28, 29, 31, 32, 27 + 256, 28 + 256}
+
+// validGenerator builds valid arguments for a precompile and then mutates a few of
+// them, so inputs get past the early checks and land near the edge cases.
+type validGenerator struct {
+	build func(random *rand.Rand) ([]precompileArg, error)
+	typed Generator // Builds fully random arguments for some inputs, may be nil
+}
+
+// Generate builds an input with zero or more mutated arguments.
+func (g *validGenerator) Generate(random *rand.Rand) ([]byte, error) {
+	if g.typed != nil && random.Intn(8) == 0 {
+		return g.typed.Generate(random)
+	}
+
+	args, err := g.build(random)
+	if err != nil {
+		return nil, err
+	}
+
+	// Zero mutations keeps the input valid
+	for range random.Intn(validMaxMutations + 1) {
+		if len(args) == 0 {
+			break
+		}
+		i := random.Intn(len(args))
+		switch random.Intn(8) {
+		case 0:
+			args = append(args[:i], args[i+1:]...)
+		case 1:
+			args = append(args[:i+1], args[i:]...)
+		default:
+			args[i].value = mutateArg(random, args[i])
+		}
+	}
+
+	var out []byte
+	for _, arg := range args {
+		out = append(out, arg.value...)
+	}
+
+	// Occasionally break the expected input length
+	switch random.Intn(16) {
+	case 0:
+		out = out[:random.Intn(len(out)+1)]
+	case 1:
+		extra := make([]byte, 1+random.Intn(64))
+		random.Read(extra)
+		out = append(out, extra...)
+	}
+	return out, nil
+}
+
+// mutateArg returns a near-valid variant of the argument.
+func mutateArg(random *rand.Rand, arg precompileArg) []byte {
+	value := append([]byte(nil), arg.value...)
+	switch arg.kind {
+	case argLength:
+		return word(new(big.Int).SetUint64(extremeLengths[random.Intn(len(extremeLengths))]))
+	case argRecoveryID:
+		return word(new(big.Int).SetUint64(recoveryIDs[random.Intn(len(recoveryIDs))]))
+	case argSecpScalar:
+		return mutateElement(random, value, secpN)
+	case argBN254Scalar:
+		return mutateElement(random, value, bn254R)
+	case argBLSScalar:
+		return mutateElement(random, value, blsR)
+	case argBLSFp, argBLSFp2:
+		return mutateCoordinate(random, value, 64, blsP)
+	case argBN254G1:
+		return mutatePoint(random, value, 32, bn254P, nil)
+	case argBN254G2:
+		return mutatePoint(random, value, 32, bn254P, bn254G2OutsideSubgroup)
+	case argBLSG1:
+		return mutatePoint(random, value, 64, blsP, blsG1OutsideSubgroup)
+	case argBLSG2:
+		return mutatePoint(random, value, 64, blsP, blsG2OutsideSubgroup)
+	case argKZGPoint:
+		return mutateCompressed(random, value)
+	}
+
+	if len(value) > 0 {
+		value[random.Intn(len(value))] ^= 1 << random.Intn(8)
+	}
+	return value
+}
+
+// mutateElement replaces a big-endian field element with a value at the modulus boundaries.
+func mutateElement(random *rand.Rand, value []byte, modulus *big.Int) []byte {
+	v := new(big.Int).SetBytes(value)
+	one := big.NewInt(1)
+	max := new(big.Int).Sub(new(big.Int).Lsh(one, uint(8*len(value))), one)
+
+	candidates := []*big.Int{
+		big.NewInt(0),
+		one,
+		new(big.Int).Sub(modulus, one),
+		modulus,
+		new(big.Int).Add(modulus, one),
+		new(big.Int).Add(v, one),
+		new(big.Int).Add(v, modulus), // Same value, but not reduced
+		max,
+		new(big.Int).Rand(random, modulus),
+	}
+	candidate := candidates[random.Intn(len(candidates))]
+	if candidate.Cmp(max) > 0 {
+		candidate = max
+	}
+	return candidate.FillBytes(make([]byte, len(value)))
+}
+
+// mutateCoordinate mutates one of the field elements in a point or extension field element.
+func mutateCoordinate(random *rand.Rand, value []byte, width int, modulus *big.Int) []byte {
+	i := random.Intn(len(value)/width) * width
+	copy(value[i:i+width], mutateElement(random, value[i:i+width], modulus))
+	return value
+}
+
+// mutatePoint returns a point that is off the curve, outside the subgroup, at infinity
+// or in the wrong coordinate order.
+func mutatePoint(random *rand.Rand, value []byte, width int, modulus *big.Int,
+	outside func(random *rand.Rand) []byte) []byte {
+	switch random.Intn(4) {
+	case 0:
+		return make([]byte, len(value))
+	case 1:
+		if outside != nil {
+			return outside(random)
+		}
+	case 2:
+		// Swap x and y, or the two parts of each extension field coordinate
+		half := len(value) / 2
+		if len(value) == 4*width {
+			half = width
+		}
+		for i := 0; i+2*half <= len(value); i += 2 * half {
+			a := append([]byte(nil), value[i:i+half]...)
+			copy(value[i:i+half], value[i+half:i+2*half])
+			copy(value[i+half:i+2*half], a)
+		}
+		return value
+	}
+	return mutateCoordinate(random, value, width, modulus)
+}
+
+// mutateCompressed mutates the flags and encoding of a compressed BLS12-381 point.
+func mutateCompressed(random *rand.Rand, value []byte) []byte {
+	switch random.Intn(4) {
+	case 0:
+		// Flip the compression, infinity or sign flag
+		value[0] ^= 0x80 >> random.Intn(3)
+	case 1:
+		infinity := make([]byte, len(value))
+		infinity[0] = 0xc0
+		return infinity
+	case 2:
+		// An x coordinate that is not reduced
+		x := blsP.FillBytes(make([]byte, len(value)))
+		x[0] |= 0x80
+		return x
+	default:
+		value[1+random.Intn(len(value)-1)] ^= 1 << random.Intn(8)
+	}
+	return value
+}
+
+func buildEcrecover(random *rand.Rand) ([]precompileArg, error) {
+	var key, hash [32]byte
+	random.Read(key[:])
+	random.Read(hash[:])
+
+	signature := ecdsa.SignCompact(secp256k1.PrivKeyFromBytes(key[:]), hash[:], false)
+	v, r, s := signature[0], signature[1:33], signature[33:]
+	if random.Intn(4) == 0 {
+		// A high s is valid for ecrecover if the recovery id is flipped
+		s = new(big.Int).Sub(secpN, new(big.Int).SetBytes(s)).FillBytes(make([]byte, 32))
+		v = 27 + 28 - v
+	}
+
+	return []precompileArg{
+		{argRaw, hash[:]},
+		{argRecoveryID, word(big.NewInt(int64(v)))},
+		{argSecpScalar, r},
+		{argSecpScalar, s},
+	}, nil
+}
+
+func buildModExp(random *rand.Rand) ([]precompileArg, error) {
+	var lengths, values []precompileArg
+	for range 3 {
+		value := make([]byte, random.Intn(65))
+		random.Read(value)
+		lengths = append(lengths, precompileArg{argLength, word(big.NewInt(int64(len(value))))})
+		values = append(values, precompileArg{argRaw, value})
+	}
+	return append(lengths, values...), nil
+}
+
+func buildBN254Add(random *rand.Rand) ([]precompileArg, error) {
+	return []precompileArg{
+		{argBN254G1, bn254G1(new(big.Int).Rand(random, bn254R))},
+		{argBN254G1, bn254G1(new(big.Int).Rand(random, bn254R))},
+	}, nil
+}
+
+func buildBN254ScalarMul(random *rand.Rand) ([]precompileArg, error) {
+	return []precompileArg{
+		{argBN254G1, bn254G1(new(big.Int).Rand(random, bn254R))},
+		{argBN254Scalar, word(new(big.Int).Rand(random, bn254R))},
+	}, nil
+}
+
+func buildBN254Pairing(random *rand.Rand) ([]precompileArg, error) {
+	var args []precompileArg
+	for _, pair := range pairingScalars(random, bn254R) {
+		args = append(args,
+			precompileArg{argBN254G1, bn254G1(pair[0])},
+			precompileArg{argBN254G2, bn254G2(pair[1])})
+	}
+	return args, nil
+}
+
+func buildBLSG1Add(random *rand.Rand) ([]precompileArg, error) {
+	return []precompileArg{
+		{argBLSG1, blsG1(new(big.Int).Rand(random, blsR))},
+		{argBLSG1, blsG1(new(big.Int).Rand(random, blsR))},
+	}, nil
+}
+
+func buildBLSG2Add(random *rand.Rand) ([]precompileArg, error) {
+	return []precompileArg{
+		{argBLSG2, blsG2(new(big.Int).Rand(random, blsR))},
+		{argBLSG2, blsG2(new(big.Int).Rand(random, blsR))},
+	}, nil
+}
+
+func buildBLSG1MultiExp(random *rand.Rand) ([]precompileArg, error) {
+	var args []precompileArg
+	for range 1 + random.Intn(4) {
+		args = append(args,
+			precompileArg{argBLSG1, blsG1(new(big.Int).Rand(random, blsR))},
+			precompileArg{argBLSScalar, word(new(big.Int).Rand(random, blsR))})
+	}
+	return args, nil
+}
+
+func buildBLSG2MultiExp(random *rand.Rand) ([]precompileArg, error) {
+	var args []precompileArg
+	for range 1 + random.Intn(4) {
+		args = append(args,
+			precompileArg{argBLSG2, blsG2(new(big.Int).Rand(random, blsR))},
+			precompileArg{argBLSScalar, word(new(big.Int).Rand(random, blsR))})
+	}
+	return args, nil
+}
+
+func buildBLSPairing(random *rand.Rand) ([]precompileArg, error) {
+	var args []precompileArg
+	for _, pair := range pairingScalars(random, blsR) {
+		args = append(args,
+			precompileArg{argBLSG1, blsG1(pair[0])},
+			precompileArg{argBLSG2, blsG2(pair[1])})
+	}
+	return args, nil
+}
+
+func buildBLSMapG1(random *rand.Rand) ([]precompileArg, error) {
+	return []precompileArg{{argBLSFp, blsElement(new(big.Int).Rand(random, blsP))}}, nil
+}
+
+func buildBLSMapG2(random *rand.Rand) ([]precompileArg, error) {
+	value := append(blsElement(new(big.Int).Rand(random, blsP)), blsElement(new(big.Int).Rand(random, blsP))...)
+	return []precompileArg{{argBLSFp2, value}}, nil
+}
+
+func buildPointEvaluation(random *rand.Rand) ([]precompileArg, error) {
+	openings, err := loadKZGOpenings()
+	if err != nil {
+		return nil, err
+	}
+
+	opening := openings[random.Intn(len(openings))]
+	args := make([]precompileArg, len(opening))
+	for i, arg := range opening {
+		args[i] = precompileArg{arg.kind, append([]byte(nil), arg.value...)}
+	}
+	return args, nil
+}
+
+// pairingScalars returns the discrete logs of pairs of G1 and G2 points. Usually the
+// last pair cancels the others out so the pairing check succeeds.
+func pairingScalars(random *rand.Rand, order *big.Int) [][2]*big.Int {
+	var pairs [][2]*big.Int
+	sum := new(big.Int)
+	for range random.Intn(4) {
+		a, b := new(big.Int).Rand(random, order), new(big.Int).Rand(random, order)
+		sum.Add(sum, new(big.Int).Mul(a, b))
+		pairs = append(pairs, [2]*big.Int{a, b})
+	}
+
+	if random.Intn(4) == 0 {
+		return pairs
+	}
+	last := new(big.Int).Neg(sum)
+	return append(pairs, [2]*big.Int{last.Mod(last, order), big.NewInt(1)})
+}
+
+// bn254G1 encodes the generator multiplied by the scalar.
+func bn254G1(scalar *big.Int) []byte {
+	_, _, g1, _ := bn254.Generators()
+	var p bn254.G1Affine
+	p.ScalarMultiplication(&g1, scalar)
+	x, y := p.X.Bytes(), p.Y.Bytes()
+	return append(x[:], y[:]...)
+}
+
+// bn254G2 encodes the generator multiplied by the scalar.
+func bn254G2(scalar *big.Int) []byte {
+	_, _, _, g2 := bn254.Generators()
+	var p bn254.G2Affine
+	p.ScalarMultiplication(&g2, scalar)
+	return encodeBN254G2(&p)
+}
+
+// encodeBN254G2 encodes a G2 point with the imaginary part of each coordinate first.
+func encodeBN254G2(p *bn254.G2Affine) []byte {
+	var out []byte
+	for _, element := range []bnfp.Element{p.X.A1, p.X.A0, p.Y.A1, p.Y.A0} {
+		b := element.Bytes()
+		out = append(out, b[:]...)
+	}
+	return out
+}
+
+// bn254G2OutsideSubgroup encodes a random point on the twist that is not in G2.
+func bn254G2OutsideSubgroup(random *rand.Rand) []byte {
+	// The twist is y^2 = x^3 + 3/(9+u)
+	var b bn254.E2
+	var three bnfp.Element
+	b.A0.SetUint64(9)
+	b.A1.SetOne()
+	b.Inverse(&b)
+	b.MulByElement(&b, three.SetUint64(3))
+
+	for {
+		var p bn254.G2Affine
+		p.X.A0.SetBigInt(new(big.Int).Rand(random, bn254P))
+		p.X.A1.SetBigInt(new(big.Int).Rand(random, bn254P))
+
+		var rhs bn254.E2
+		rhs.Square(&p.X).Mul(&rhs, &p.X).Add(&rhs, &b)
+		if rhs.Legendre() != 1 {
+			continue
+		}
+		p.Y.Sqrt(&rhs)
+		if !p.IsInSubGroup() {
+			return encodeBN254G2(&p)
+		}
+	}
+}
+
+// blsElement encodes a base field element padded to 64 bytes.
+func blsElement(value *big.Int) []byte {
+	return value.FillBytes(make([]byte, 64))
+}
+
+// blsG1 encodes the generator multiplied by the scalar.
+func blsG1(scalar *big.Int) []byte {
+	_, _, g1, _ := bls12381.Generators()
+	var p bls12381.G1Affine
+	p.ScalarMultiplication(&g1, scalar)
+	return encodeBLSG1(&p)
+}
+
+// blsG2 encodes the generator multiplied by the scalar.
+func blsG2(scalar *big.Int) []byte {
+	_, _, _, g2 := bls12381.Generators()
+	var p bls12381.G2Affine
+	p.ScalarMultiplication(&g2, scalar)
+	return encodeBLSG2(&p)
+}
+
+func encodeBLSG1(p *bls12381.G1Affine) []byte {
+	var out []byte
+	for _, element := range []blsfp.Element{p.X, p.Y} {
+		out = append(out, blsElement(element.BigInt(new(big.Int)))...)
+	}
+	return out
+}
+
+func encodeBLSG2(p *bls12381.G2Affine) []byte {
+	var out []byte
+	for _, element := range []blsfp.Element{p.X.A0, p.X.A1, p.Y.A0, p.Y.A1} {
+		out = append(out, blsElement(element.BigInt(new(big.Int)))...)
+	}
+	return out
+}
+
+// blsG1OutsideSubgroup encodes a random point on the curve that is not in G1.
+func blsG1OutsideSubgroup(random *rand.Rand) []byte {
+	var b blsfp.Element
+	b.SetUint64(4)
+
+	for {
+		var p bls12381.G1Affine
+		p.X.SetBigInt(new(big.Int).Rand(random, blsP))
+
+		var rhs blsfp.Element
+		rhs.Square(&p.X).Mul(&rhs, &p.X).Add(&rhs, &b)
+		if p.Y.Sqrt(&rhs) == nil {
+			continue
+		}
+		if !p.IsInSubGroup() {
+			return encodeBLSG1(&p)
+		}
+	}
+}
+
+// blsG2OutsideSubgroup encodes a random point on the twist that is not in G2.
+func blsG2OutsideSubgroup(random *rand.Rand) []byte {
+	// The twist is y^2 = x^3 + 4(1+u)
+	var b bls12381.E2
+	b.A0.SetUint64(4)
+	b.A1.SetUint64(4)
+
+	for {
+		var p bls12381.G2Affine
+		p.X.A0.SetBigInt(new(big.Int).Rand(random, blsP))
+		p.X.A1.SetBigInt(new(big.Int).Rand(random, blsP))
+
+		var rhs bls12381.E2
+		rhs.Square(&p.X).Mul(&rhs, &p.X).Add(&rhs, &b)
+		if rhs.Legendre() != 1 {
+			continue
+		}
+		p.Y.Sqrt(&rhs)
+		if !p.IsInSubGroup() {
+			return encodeBLSG2(&p)
+		}
+	}
+}
+
+var kzg struct {
+	once     sync.Once
+	openings [][]precompileArg
+	err      error
+}
+
+// loadKZGOpenings computes a few valid point evaluation inputs. This is slow, so it
+// happens once and always from the same seed.
+func loadKZGOpenings() ([][]precompileArg, error) {
+	kzg.once.Do(func() {
+		context, err := gokzg4844.NewContext4096Secure()
+		if err != nil {
+			kzg.err = fmt.Errorf("failed to create KZG context: %w", err)
+			return
+		}
+
+		random := rand.New(rand.NewSource(0))
+		for range kzgOpenings {
+			var blob gokzg4844.Blob
+			var z gokzg4844.Scalar
+			for i := 0; i < len(blob); i += len(z) {
+				random.Read(blob[i : i+len(z)])
+				blob[i] &= 0x3f // Keep field elements below the modulus
+			}
+			random.Read(z[:])
+			z[0] &= 0x3f
+
+			commitment, err := context.BlobToKZGCommitment(&blob, 0)
+			if err != nil {
+				kzg.err = fmt.Errorf("failed to compute commitment: %w", err)
+				return
+			}
+			proof, y, err := context.ComputeKZGProof(&blob, z, 0)
+			if err != nil {
+				kzg.err = fmt.Errorf("failed to compute proof: %w", err)
+				return
+			}
+
+			versionedHash := sha256.Sum256(commitment[:])
+			versionedHash[0] = 0x01
+			kzg.openings = append(kzg.openings, []precompileArg{
+				{argRaw, versionedHash[:]},
+				{argBLSScalar, z[:]},
+				{argBLSScalar, y[:]},
+				{argKZGPoint, commitment[:]},
+				{argKZGPoint, proof[:]},
+			})
+		}
+	})
+	return kzg.openings, kzg.err
+}
+
+// precompileBuilders maps precompile method names to builders of valid arguments.
+var precompileBuilders = map[string]func(random *rand.Rand) ([]precompileArg, error){
+	"ecrecover":          buildEcrecover,
+	"bigModExp":          buildModExp,
+	"bn256Add":           buildBN254Add,
+	"bn256ScalarMul":     buildBN254ScalarMul,
+	"bn256Pairing":       buildBN254Pairing,
+	"kzgPointEvaluation": buildPointEvaluation,
+	"bls12381G1Add":      buildBLSG1Add,
+	"bls12381G1MultiExp": buildBLSG1MultiExp,
+	"bls12381G2Add":      buildBLSG2Add,
+	"bls12381G2MultiExp": buildBLSG2MultiExp,
+	"bls12381Pairing":    buildBLSPairing,
+	"bls12381MapG1":      buildBLSMapG1,
+	"bls12381MapG2":      buildBLSMapG2,
+}
+
+// precompileGenerator returns the generator for a precompile. Precompiles without a
+// builder of valid arguments only get typed arguments.
+func precompileGenerator(method string) Generator {
+	typed := typedPrecompileGenerators[method]
+	build, ok := precompileBuilders[method]
+	if !ok {
+		return typed
+	}
+	return &validGenerator{build: build, typed: typed}
+}
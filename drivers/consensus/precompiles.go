@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// executionFork is the corpus directory for execution-layer inputs.
+const executionFork = "execution"
+
+// precompileVectorFiles maps go-ethereum's core/vm/testdata/precompiles file names
+// (without the "fail-" prefix or ".json" suffix) to the precompile method names
+// that processors understand.
+var precompileVectorFiles = map[string]string{
+	"ecRecover":       "ecrecover",
+	"modexp":          "bigModExp",
+	"modexp_eip2565":  "bigModExp",
+	"bn256Add":        "bn256Add",
+	"bn256ScalarMul":  "bn256ScalarMul",
+	"bn256Pairing":    "bn256Pairing",
+	"blake2F":         "blake2F",
+	"blake2f":         "blake2F",
+	"pointEvaluation": "kzgPointEvaluation",
+	"blsG1Add":        "bls12381G1Add",
+	"blsG1Mul":        "bls12381G1MultiExp", // A single-pair MSM is a multiplication
+	"blsG1MultiExp":   "bls12381G1MultiExp",
+	"blsG2Add":        "bls12381G2Add",
+	"blsG2Mul":        "bls12381G2MultiExp", // A single-pair MSM is a multiplication
+	"blsG2MultiExp":   "bls12381G2MultiExp",
+	"blsPairing":      "bls12381Pairing",
+	"blsMapG1":        "bls12381MapG1",
+	"blsMapG2":        "bls12381MapG2",
+}
+
+// PrecompileVector is a single test vector in go-ethereum's precompile format.
+// Success vectors set Expected, failure vectors set ExpectedError.
+type PrecompileVector struct {
+	Input         string
+	Expected      string `json:",omitempty"`
+	ExpectedError string `json:",omitempty"`
+	Gas           uint64 `json:",omitempty"`
+	Name          string
+	Source        string `json:",omitempty"` // File the vector was imported from
+}
+
+// ImportPrecompileVectors reads go-ethereum precompile vectors from a local path and
+// populates corpus/execution/<precompile>/. The path can either be a directory of
+// vector files or a go-ethereum checkout. Expected results are kept next to the
+// inputs in an expected/ subdirectory so they can be used as an oracle.
+func ImportPrecompileVectors(path string) error {
+	vectorDir := path
+	gethDir := filepath.Join(path, "core", "vm", "testdata", "precompiles")
+	if exists, err := directoryExists(gethDir); err != nil {
+		return err
+	} else if exists {
+		vectorDir = gethDir
+	}
+
+	entries, err := os.ReadDir(vectorDir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		base := strings.TrimSuffix(entry.Name(), ".json")
+		method, ok := precompileVectorFiles[strings.TrimPrefix(base, "fail-")]
+		if !ok {
+			fmt.Printf("Skipping unknown precompile vectors: %s\n", entry.Name())
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(vectorDir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read vectors: %w", err)
+		}
+		var vectors []PrecompileVector
+		if err := json.Unmarshal(data, &vectors); err != nil {
+			return fmt.Errorf("failed to decode vectors %s: %w", entry.Name(), err)
+		}
+
+		for _, vector := range vectors {
+			vector.Source = entry.Name()
+			if err := addPrecompileVector(method, vector); err != nil {
+				return fmt.Errorf("failed to import vector %s: %w", vector.Name, err)
+			}
+		}
+		fmt.Printf("Populated %v.%v (count: %v) (source: %v)\n",
+			executionFork, method, len(vectors), entry.Name())
+	}
+
+	return nil
+}
+
+// addPrecompileVector writes the vector's input to the corpus and records the
+// expected result. Vectors sharing an input (e.g. gas repricings) are merged.
+func addPrecompileVector(method string, vector PrecompileVector) error {
+	input, err := hex.DecodeString(vector.Input)
+	if err != nil {
+		return fmt.Errorf("failed to decode input: %w", err)
+	}
+
+	hash := sha256.Sum256(input)
+	hashHex := fmt.Sprintf("%x", hash[:])
+
+	outputDir := filepath.Join("corpus", executionFork, method)
+	expectedDir := filepath.Join(outputDir, "expected")
+	if err := os.MkdirAll(expectedDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(outputDir, hashHex+".bin"), input, 0644); err != nil {
+		return fmt.Errorf("failed to write input: %w", err)
+	}
+
+	expectedPath := filepath.Join(expectedDir, hashHex+".json")
+	var expected []PrecompileVector
+	if data, err := os.ReadFile(expectedPath); err == nil {
+		if err := json.Unmarshal(data, &expected); err != nil {
+			return fmt.Errorf("failed to decode expected results: %w", err)
+		}
+	}
+	for _, existing := range expected {
+		if existing.Source == vector.Source && existing.Name == vector.Name {
+			return nil
+		}
+	}
+	expected = append(expected, vector)
+	sort.Slice(expected, func(i, j int) bool {
+		if expected[i].Source != expected[j].Source {
+			return expected[i].Source < expected[j].Source
+		}
+		return expected[i].Name < expected[j].Name
+	})
+
+	data, err := json.MarshalIndent(expected, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode expected results: %w", err)
+	}
+	return os.WriteFile(expectedPath, data, 0644)
+}
+
+// Oracle provides the expected results of imported precompile vectors.
+type Oracle struct {
+	mu      sync.Mutex                                 // Protects the cache map
+	entries map[string]map[[32]byte][]PrecompileVector // Method to input hash to vectors
+}
+
+// NewOracle creates a new Oracle.
+func NewOracle() *Oracle {
+	return &Oracle{
+		entries: make(map[string]map[[32]byte][]PrecompileVector),
+	}
+}
+
+// Global oracle instance
+var oracle = NewOracle()
+
+// Lookup returns the vectors whose input is exactly the given input.
+func (o *Oracle) Lookup(method string, input []byte) ([]PrecompileVector, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entries, loaded := o.entries[method]
+	if !loaded {
+		var err error
+		entries, err = loadExpected(method)
+		if err != nil {
+			return nil, err
+		}
+		o.entries[method] = entries
+	}
+	return entries[sha256.Sum256(input)], nil
+}
+
+// Check compares a result against the expected results for the input. Vectors
+// which expect an error only require the result to be rejected. It returns the names
+// of vectors that disagree with the result.
+func (o *Oracle) Check(method string, input []byte, result Result) ([]string, error) {
+	vectors, err := o.Lookup(method, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []string
+	for _, vector := range vectors {
+		if vector.ExpectedError != "" {
+			if !result.Err {
+				mismatches = append(mismatches, vector.Name)
+			}
+			continue
+		}
+		expected, err := hex.DecodeString(vector.Expected)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode expected output: %w", err)
+		}
+		if result.Err || !bytes.Equal(expected, result.Output) {
+			mismatches = append(mismatches, vector.Name)
+		}
+	}
+	return mismatches, nil
+}
+
+// loadExpected reads every expected result recorded for a precompile.
+func loadExpected(method string) (map[[32]byte][]PrecompileVector, error) {
+	entries := make(map[[32]byte][]PrecompileVector)
+
+	dir := filepath.Join("corpus", executionFork, method, "expected")
+	exists, err := directoryExists(dir)
+	if err != nil || !exists {
+		return entries, err
+	}
+
+	files, err := listFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read expected results: %w", err)
+		}
+		var vectors []PrecompileVector
+		if err := json.Unmarshal(data, &vectors); err != nil {
+			return nil, fmt.Errorf("failed to decode expected results %s: %w", file, err)
+		}
+		for _, vector := range vectors {
+			input, err := hex.DecodeString(vector.Input)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode input: %w", err)
+			}
+			hash := sha256.Sum256(input)
+			entries[hash] = append(entries[hash], vector)
+		}
+	}
+	return entries, nil
+}
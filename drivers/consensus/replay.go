@@ -0,0 +1,283 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Exit codes of the replay subcommand.
+const (
+	replayAgreed     = 0 // Every client returned an equivalent result
+	replayReproduced = 1 // The divergence still reproduces
+	replayFailed     = 2 // The input couldn't be replayed
+)
+
+// replay sends a saved input to the requested processors once and reports their results.
+// The path is either a finding's directory or a file with the raw input.
+func replay(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	targetName := flags.String("target", "",
+		"method for processors to run, required when replaying a file")
+	clientList := flags.String("clients", "",
+		"comma-separated processors to wait for, required when replaying a file")
+	comparator := flags.String("comparator", "",
+		"how results are compared instead of the finding's or target's comparator")
+	output := flags.String("output", "",
+		"SSZ object type of the outputs to diff instead of the finding's")
+	timeout := flags.Duration("timeout", 5*time.Minute, "how long to wait for processors")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s replay [flags] <finding-dir|file>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return replayFailed
+	}
+	path := flags.Arg(0)
+
+	// Read the input, and the target and clients from the finding if there is one
+	var input []byte
+	var clientNames []string
+	var finding *Finding
+	comparatorName := *comparator
+	outputObject := *output
+	isDir, err := directoryExists(path)
+	if err != nil {
+		fmt.Printf("Error checking if directory exists: %v\n", err)
+		return replayFailed
+	}
+	if isDir {
+		finding, err = LoadFinding(path)
+		if err != nil {
+			fmt.Println(err)
+			return replayFailed
+		}
+		input, err = os.ReadFile(filepath.Join(path, finding.Input))
+		if err != nil {
+			fmt.Printf("Error reading finding input: %v\n", err)
+			return replayFailed
+		}
+		if *targetName == "" {
+			*targetName = finding.Target
+		}
+		for _, client := range finding.Clients {
+			clientNames = append(clientNames, client.Name)
+		}
+		if comparatorName == "" {
+			comparatorName = finding.Comparator
+		}
+		if outputObject == "" {
+			outputObject = finding.OutputObject
+		}
+	} else {
+		input, err = os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Error reading input: %v\n", err)
+			return replayFailed
+		}
+	}
+	if *clientList != "" {
+		clientNames = strings.Split(*clientList, ",")
+	}
+	if *targetName == "" || len(clientNames) == 0 {
+		fmt.Println("Replaying a file requires -target and -clients")
+		return replayFailed
+	}
+
+	// Slow findings are replayed against the factor and budget they were found with
+	var slowFactor float64
+	var slowBudget time.Duration
+	if finding != nil && len(finding.Slow) > 0 {
+		slowFactor, slowBudget, err = finding.slowLimits()
+		if err != nil {
+			fmt.Println(err)
+			return replayFailed
+		}
+	}
+
+	target, err := lookupTarget(*targetName)
+	if err != nil {
+		fmt.Println(err)
+		return replayFailed
+	}
+	if comparatorName != "" {
+		target.Comparator, err = lookupComparator(comparatorName)
+		if err != nil {
+			fmt.Println(err)
+			return replayFailed
+		}
+	}
+	if outputObject != "" {
+		if err := target.SetOutput(outputObject); err != nil {
+			fmt.Println(err)
+			return replayFailed
+		}
+	}
+
+	server, err := connectClients(target, clientNames, *timeout)
+	if err != nil {
+		fmt.Println(err)
+		return replayFailed
+	}
+	defer server.Close()
+
+	// A client whose result changed is only compared with itself
+	if finding != nil && finding.Nondeterministic != "" {
+		return replayNondeterministic(server, path, target, clientNames, input)
+	}
+
+	// Only send the input to the requested clients, others may have registered too
+	results, crashed := processClients(server, clientNames, input)
+
+	inputHash := sha256.Sum256(input)
+	fmt.Printf("Replayed %s on %s (input: %d bytes, sha256: %x)\n", path, target.Name, len(input), inputHash)
+	status := replayAgreed
+	for _, clientName := range clientNames {
+		result, ok := results[clientName]
+		if slices.Contains(crashed, clientName) {
+			fmt.Printf("Client: %s, Status: crashed\n", clientName)
+			continue
+		} else if !ok {
+			fmt.Printf("Client: %s, no result\n", clientName)
+			status = replayFailed
+			continue
+		}
+		outputHash := sha256.Sum256(result.Output)
+		fmt.Printf("Client: %s, Status: %s, Duration: %s, Output sha256: %x\n",
+			clientName, resultStatus(result), result.Duration, outputHash)
+	}
+	if status == replayFailed {
+		return status
+	}
+	if len(crashed) > 0 {
+		fmt.Printf("Clients crashed: %s\n", strings.Join(crashed, ","))
+		return replayReproduced
+	}
+
+	if finding != nil && len(finding.Slow) > 0 {
+		slow := slowClients(results, slowFactor, slowBudget)
+		if len(slow) > 0 {
+			fmt.Printf("Clients were slow: %s (factor: %g, budget: %s)\n",
+				strings.Join(slow, ","), slowFactor, slowBudget)
+			return replayReproduced
+		}
+		fmt.Printf("No client was slow (factor: %g, budget: %s)\n", slowFactor, slowBudget)
+	}
+
+	vote := NewVote(target.Comparator, results)
+	if vote.Agreed() {
+		fmt.Printf("Results agree (comparator: %s)\n", target.Comparator.Name())
+		return replayAgreed
+	}
+	fmt.Printf("Values are different (comparator: %s):\n%s", target.Comparator.Name(), vote)
+	if diff, _ := DiffOutputs(target, vote); len(diff) > 0 {
+		fmt.Printf("Differing fields:\n%s\n", strings.Join(diff, "\n"))
+	}
+	return replayReproduced
+}
+
+// replayNondeterministic sends the input of a finding for a client whose result changed to
+// each client twice, and reports whether any client's result changed again.
+func replayNondeterministic(server *Server, path string, target *Target, clientNames []string, input []byte) int {
+	inputHash := sha256.Sum256(input)
+	fmt.Printf("Replayed %s twice on %s (input: %d bytes, sha256: %x)\n", path, target.Name, len(input), inputHash)
+	var crashed, changed []string
+	for _, clientName := range clientNames {
+		first, second, err := sendTwice(server, clientName, input)
+		if errors.Is(err, errClientCrashed) {
+			fmt.Printf("Client: %s, Status: crashed\n", clientName)
+			crashed = append(crashed, clientName)
+			continue
+		} else if err != nil {
+			fmt.Printf("Client: %s, no result: %v\n", clientName, err)
+			return replayFailed
+		}
+		for run, result := range []Result{first, second} {
+			outputHash := sha256.Sum256(result.Output)
+			fmt.Printf("Client: %s, Run: %d, Status: %s, Duration: %s, Output sha256: %x\n",
+				clientName, run+1, resultStatus(result), result.Duration, outputHash)
+		}
+		if resultChanged(first, second) {
+			changed = append(changed, clientName)
+		}
+	}
+	if len(crashed) > 0 {
+		fmt.Printf("Clients crashed: %s\n", strings.Join(crashed, ","))
+	}
+	if len(changed) > 0 {
+		fmt.Printf("Results changed when sent again: %s\n", strings.Join(changed, ","))
+	}
+	if len(crashed) > 0 || len(changed) > 0 {
+		return replayReproduced
+	}
+	fmt.Println("Results are the same when sent again")
+	return replayAgreed
+}
+
+// connectClients starts a server for the target and waits until every named client has
+// registered. The server is closed if the driver is interrupted.
+func connectClients(target *Target, clientNames []string, timeout time.Duration) (*Server, error) {
+	server, err := NewServer(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start server: %w", err)
+	}
+
+	// Clean up if interrupted
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signalChan
+		fmt.Println("\nReceived interrupt")
+		server.Close()
+		os.Exit(replayFailed)
+	}()
+
+	if err := waitForClients(server, clientNames, timeout); err != nil {
+		server.Close()
+		return nil, err
+	}
+	return server, nil
+}
+
+// waitForClients waits until every named client has registered.
+func waitForClients(server *Server, clientNames []string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		var missing []string
+		registered := server.ClientNames()
+		for _, clientName := range clientNames {
+			if !slices.Contains(registered, clientName) {
+				missing = append(missing, clientName)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for clients: %s", strings.Join(missing, ","))
+		}
+		fmt.Printf("Waiting for clients: %s\n", strings.Join(missing, ","))
+		time.Sleep(1 * time.Second)
+	}
+}
+
+// processClients sends an input to the named clients only, since others may have
+// registered too, and returns their results and the names of those which crashed.
+func processClients(server *Server, clientNames []string, input []byte) (map[string]Result, []string) {
+	results, crashedClients, _ := server.ProcessClients(clientNames, input)
+	var crashed []string
+	for _, client := range crashedClients {
+		crashed = append(crashed, client.Name)
+	}
+	return results, crashed
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gen2brain/shm"
+)
+
+const (
+	socketName = "/tmp/eth-cl-fuzz"
+
+	maxClientNameLength = 32
+
+	inputShmKey = 1000
+	shmMaxSize  = 100 * 1024 * 1024 // 100 MiB
+	shmPerm     = 0666
+)
+
+type Client struct {
+	Name      string
+	Version   string // Version of the client under test, if the processor sent one
+	Conn      net.Conn
+	ShmId     int
+	ShmBuffer []byte
+	Method    string
+}
+
+// detachAndDelete detaches and deletes a shared memory region.
+func detachAndDelete(shmId int, shmBuffer []byte) {
+	// Cleanup driver shared memory
+	if err := shm.Dt(shmBuffer); err != nil {
+		fmt.Printf("Failed to detach driver shared memory: %v\n", err)
+	}
+	if _, err := shm.Ctl(shmId, shm.IPC_RMID, nil); err != nil {
+		fmt.Printf("Failed to remove driver shared memory: %v\n", err)
+	}
+}
+
+// newSharedMemory creates a new shared memory segment.
+func newSharedMemory(shmKey int) (int, []byte, error) {
+	// Create the shared memory segment
+	shmId, err := shm.Get(shmKey, shmMaxSize, shmPerm|shm.IPC_CREAT|shm.IPC_EXCL)
+	if err != nil {
+		fmt.Printf("Error creating shared memory: %v\n", err)
+		return 0, nil, err
+	}
+
+	// Attach to the shared memory segment
+	shmBuffer, err := shm.At(shmId, 0, 0)
+	if err != nil {
+		fmt.Printf("Error attaching to shared memory: %v\n", err)
+		return 0, nil, err
+	}
+
+	return shmId, shmBuffer, nil
+}
+
+// Server registers processors on the unix socket and sends them inputs through shared
+// memory.
+type Server struct {
+	mu             sync.Mutex // Protects the clients map
+	clients        map[string]*Client
+	registered     int // Registrations so far, used for output shm keys
+	target         *Target
+	inputShmId     int
+	inputShmBuffer []byte
+	listener       net.Listener
+	closed         bool          // The shared memory has been removed
+	timeout        time.Duration // How long a client may take to process an input, unlimited if zero
+}
+
+// NewServer creates the input shared memory and the socket, and registers processors
+// for the target in the background.
+func NewServer(target *Target) (*Server, error) {
+	inputShmId, inputShmBuffer, err := newSharedMemory(inputShmKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create input shm: %w", err)
+	}
+
+	// Create unix domain socket for small communications
+	os.Remove(socketName)
+	listener, err := net.Listen("unix", socketName)
+	if err != nil {
+		detachAndDelete(inputShmId, inputShmBuffer)
+		return nil, fmt.Errorf("failed to create Unix domain socket: %w", err)
+	}
+
+	s := &Server{
+		clients:        make(map[string]*Client),
+		target:         target,
+		inputShmId:     inputShmId,
+		inputShmBuffer: inputShmBuffer,
+		listener:       listener,
+	}
+	go s.acceptClients()
+	return s, nil
+}
+
+// SetTimeout sets how long a client may take to process an input before it is
+// disconnected, or zero for no limit.
+func (s *Server) SetTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeout = timeout
+}
+
+// Close disconnects every client and removes the shared memory and the socket.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	detachAndDelete(s.inputShmId, s.inputShmBuffer)
+	for _, client := range s.clients {
+		client.Conn.Close()
+		detachAndDelete(client.ShmId, client.ShmBuffer)
+	}
+	s.mu.Unlock()
+	s.listener.Close()
+	os.Remove(socketName)
+}
+
+// ClientNames returns the sorted names of registered clients.
+func (s *Server) ClientNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var clientNames []string
+	for clientName := range s.clients {
+		clientNames = append(clientNames, clientName)
+	}
+	sort.Strings(clientNames)
+	return clientNames
+}
+
+// Versions returns the version each registered client reported.
+func (s *Server) Versions() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := make(map[string]string)
+	for clientName, client := range s.clients {
+		versions[clientName] = client.Version
+	}
+	return versions
+}
+
+// acceptClients registers processors until the listener is closed.
+func (s *Server) acceptClients() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			// Don't print error if we close the registration listener
+			if !strings.Contains(err.Error(), "use of closed network connection") {
+				fmt.Printf("Error accepting connection: %v\n", err)
+			}
+			return
+		}
+		s.register(conn)
+	}
+}
+
+// register sends a new processor the shared memory ids and the method to run.
+func (s *Server) register(conn net.Conn) {
+	clientNameBytes := make([]byte, maxClientNameLength)
+	n, err := conn.Read(clientNameBytes)
+	if err != nil {
+		fmt.Printf("Error reading client name: %v\n", err)
+		conn.Close()
+		return
+	}
+	// Processors may send their name as name@version
+	clientName, clientVersion, _ := strings.Cut(string(clientNameBytes[:n]), "@")
+
+	inputShmIdBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(inputShmIdBytes, uint32(s.inputShmId))
+	_, err = conn.Write([]byte(inputShmIdBytes))
+	if err != nil {
+		fmt.Printf("Error writing to client %s: %v\n", clientName, err)
+		conn.Close()
+		return
+	}
+
+	s.registered++
+	outputShmKey := inputShmKey + s.registered
+	outputShmId, clientShmBuffer, err := newSharedMemory(outputShmKey)
+	if err != nil {
+		fmt.Printf("Error creating client output shm: %v\n", err)
+		conn.Close()
+		return
+	}
+
+	outputShmIdBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(outputShmIdBytes, uint32(outputShmId))
+	_, err = conn.Write([]byte(outputShmIdBytes))
+	if err != nil {
+		fmt.Printf("Error writing to client %s: %v\n", clientName, err)
+		conn.Close()
+		detachAndDelete(outputShmId, clientShmBuffer)
+		return
+	}
+
+	_, err = conn.Write([]byte(s.target.Name))
+	if err != nil {
+		fmt.Printf("Error writing to client %s: %v\n", clientName, err)
+		conn.Close()
+		detachAndDelete(outputShmId, clientShmBuffer)
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		// Closing already removed the other shared memory
+		conn.Close()
+		detachAndDelete(outputShmId, clientShmBuffer)
+	} else if _, exists := s.clients[clientName]; !exists {
+		s.clients[clientName] = &Client{
+			Name:      clientName,
+			Version:   clientVersion,
+			Conn:      conn,
+			ShmId:     outputShmId,
+			ShmBuffer: clientShmBuffer,
+			Method:    s.target.Name,
+		}
+		fmt.Printf("Registered new client: %s\n", clientName)
+	} else {
+		fmt.Printf("Client %s is already registered\n", clientName)
+		conn.Close()
+		detachAndDelete(outputShmId, clientShmBuffer)
+	}
+	s.mu.Unlock()
+}
+
+// errClientCrashed is returned when a client disconnects while processing an input.
+var errClientCrashed = errors.New("client disconnected while processing the input")
+
+// errClientTimedOut is returned when a client takes longer than the timeout to process
+// an input.
+var errClientTimedOut = errors.New("client timed out processing the input")
+
+// Process sends an input to every registered client and waits for their results.
+// Clients which fail to respond are disconnected and have no result; those which
+// disconnected while processing the input are returned as crashed, and those which
+// took longer than the timeout as timed out. Outputs refer to the clients' shared
+// memory and are only valid until the next call.
+func (s *Server) Process(input []byte) (map[string]Result, []*Client, []*Client) {
+	return s.ProcessClients(nil, input)
+}
+
+// ProcessClients is like Process, but only sends the input to the named clients, or
+// to every client if clientNames is nil. Other clients don't see the input.
+func (s *Server) ProcessClients(clientNames []string, input []byte) (map[string]Result, []*Client, []*Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, nil, nil
+	}
+
+	// Copy the input into the input buffer
+	copy(s.inputShmBuffer, input)
+	wg := &sync.WaitGroup{}
+	muResult := &sync.Mutex{}
+	results := make(map[string]Result)
+	var disconnected []*Client
+	var crashed []*Client
+	var timedOut []*Client
+	for _, client := range s.clients {
+		if clientNames != nil && !slices.Contains(clientNames, client.Name) {
+			continue
+		}
+		wg.Add(1)
+		go func(client *Client) {
+			defer wg.Done()
+			result, err := exchange(client, len(input), s.timeout)
+			muResult.Lock()
+			defer muResult.Unlock()
+			if err != nil {
+				disconnected = append(disconnected, client)
+				if errors.Is(err, errClientCrashed) {
+					crashed = append(crashed, client)
+				} else if errors.Is(err, errClientTimedOut) {
+					timedOut = append(timedOut, client)
+				}
+				return
+			}
+			results[client.Name] = result
+		}(client)
+	}
+	wg.Wait()
+
+	for _, client := range disconnected {
+		s.remove(client)
+	}
+	sort.Slice(crashed, func(i, j int) bool { return crashed[i].Name < crashed[j].Name })
+	sort.Slice(timedOut, func(i, j int) bool { return timedOut[i].Name < timedOut[j].Name })
+	return results, crashed, timedOut
+}
+
+// ProcessClient sends an input to a single registered client and waits for its result.
+func (s *Server) ProcessClient(clientName string, input []byte) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clients[clientName]
+	if s.closed || !ok {
+		return Result{}, fmt.Errorf("client %s is not registered", clientName)
+	}
+	copy(s.inputShmBuffer, input)
+	result, err := exchange(client, len(input), s.timeout)
+	if err != nil {
+		s.remove(client)
+	}
+	return result, err
+}
+
+// Disconnect removes a registered client, e.g. before its processor is restarted.
+func (s *Server) Disconnect(clientName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if client, ok := s.clients[clientName]; ok && !s.closed {
+		s.remove(client)
+	}
+}
+
+// remove disconnects a client and deletes its output shared memory. The caller must
+// hold the lock.
+func (s *Server) remove(client *Client) {
+	client.Conn.Close()
+	detachAndDelete(client.ShmId, client.ShmBuffer)
+	delete(s.clients, client.Name)
+}
+
+// exchange sends a client the size of the input and reads the size of its response,
+// waiting at most the timeout unless it is zero.
+func exchange(client *Client, inputSize int, timeout time.Duration) (Result, error) {
+	// Send the input size to the client
+	start := time.Now()
+	sizeBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(sizeBytes, uint32(inputSize))
+	_, err := client.Conn.Write([]byte(sizeBytes))
+	if err != nil {
+		if strings.Contains(err.Error(), "broken pipe") {
+			fmt.Printf("Client disconnected: %v\n", client.Name)
+		} else {
+			fmt.Printf("Error writing to client %s: %v\n", client.Name, err)
+		}
+		return Result{}, err
+	}
+
+	// Wait for a response size. The client has the input, so losing it now is a crash.
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = start.Add(timeout)
+	}
+	if err := client.Conn.SetReadDeadline(deadline); err != nil {
+		fmt.Printf("Error setting deadline of client %s: %v\n", client.Name, err)
+		return Result{}, err
+	}
+	responseSizeBytes := make([]byte, 4)
+	_, err = io.ReadFull(client.Conn, responseSizeBytes)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		fmt.Printf("Client %s timed out processing the input after %s\n", client.Name, timeout)
+		return Result{}, fmt.Errorf("%w: %v", errClientTimedOut, err)
+	}
+	if err != nil {
+		fmt.Printf("Client %s crashed while processing the input: %v\n", client.Name, err)
+		return Result{}, fmt.Errorf("%w: %v", errClientCrashed, err)
+	}
+	responseSize := binary.BigEndian.Uint32(responseSizeBytes)
+	result := Result{Err: responseSize&responseErrorFlag != 0, Duration: time.Since(start)}
+	outputSize := int(responseSize &^ responseErrorFlag)
+	if outputSize > len(client.ShmBuffer) {
+		// The output can't be in shared memory, so the client is broken
+		fmt.Printf("Client %s crashed while processing the input: response of %d bytes is larger than "+
+			"its %d-byte buffer\n", client.Name, outputSize, len(client.ShmBuffer))
+		return Result{}, fmt.Errorf("%w: response of %d bytes", errClientCrashed, outputSize)
+	}
+	result.Output = client.ShmBuffer[:outputSize]
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// slowMinDuration is the shortest duration compared with the other clients', since
+// scheduling noise dominates shorter ones.
+const slowMinDuration = 50 * time.Millisecond
+
+// slowClients returns the sorted clients which took more than factor times the median
+// duration of the other clients, or longer than the budget. A zero factor or budget
+// disables that check.
+func slowClients(results map[string]Result, factor float64, budget time.Duration) []string {
+	var slow []string
+	for clientName, result := range results {
+		if budget > 0 && result.Duration > budget {
+			slow = append(slow, clientName)
+			continue
+		}
+		if factor <= 0 || result.Duration < slowMinDuration || len(results) < 2 {
+			continue
+		}
+		var others []time.Duration
+		for otherName, other := range results {
+			if otherName != clientName {
+				others = append(others, other.Duration)
+			}
+		}
+		if float64(result.Duration) > factor*float64(medianDuration(others)) {
+			slow = append(slow, clientName)
+		}
+	}
+	sort.Strings(slow)
+	return slow
+}
+
+// slowLimits returns the factor and budget a slow finding's durations were compared with,
+// or an error if it has neither, e.g. because it was saved before they were recorded.
+func (f *Finding) slowLimits() (float64, time.Duration, error) {
+	if f.SlowFactor <= 0 && f.SlowBudget == "" {
+		return 0, 0, fmt.Errorf("finding %s has no slow factor or budget to compare durations with", f.ID)
+	}
+	var budget time.Duration
+	if f.SlowBudget != "" {
+		var err error
+		budget, err = time.ParseDuration(f.SlowBudget)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to parse slow budget of finding %s: %w", f.ID, err)
+		}
+	}
+	return f.SlowFactor, budget, nil
+}
+
+// medianDuration returns the median of durations, which must not be empty.
+func medianDuration(durations []time.Duration) time.Duration {
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// SlowSignature describes slow clients by the signature of the clients' results, which
+// clients timed out and which clients were slow.
+func SlowSignature(target *Target, vote *Vote, slow []string, timedOut []*Client) string {
+	statuses := make(map[string]string)
+	for _, client := range timedOut {
+		statuses[client.Name] = statusTimedOut
+	}
+	return FindingSignature(target, vote, nil, statuses) + "; slow=" + strings.Join(slow, ",")
+}
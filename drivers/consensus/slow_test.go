@@ -0,0 +1,132 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSlowClients(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name      string
+		durations map[string]time.Duration
+		factor    float64
+		budget    time.Duration
+		want      []string
+	}{
+		{
+			name:      "slow client",
+			durations: map[string]time.Duration{"golang": 10 * ms, "java": 12 * ms, "rust": 500 * ms},
+			factor:    10,
+			want:      []string{"rust"},
+		},
+		{
+			name:      "under the factor",
+			durations: map[string]time.Duration{"golang": 10 * ms, "java": 12 * ms, "rust": 100 * ms},
+			factor:    10,
+		},
+		{
+			// Scheduling noise dominates short durations, however fast the others are
+			name:      "under the floor",
+			durations: map[string]time.Duration{"golang": 10 * time.Microsecond, "rust": 49 * ms},
+			factor:    10,
+		},
+		{
+			name:      "at the floor",
+			durations: map[string]time.Duration{"golang": 1 * ms, "rust": slowMinDuration},
+			factor:    10,
+			want:      []string{"rust"},
+		},
+		{
+			// Each client is compared with the other, so only the slow one is reported
+			name:      "two clients",
+			durations: map[string]time.Duration{"golang": 60 * ms, "rust": 900 * ms},
+			factor:    10,
+			want:      []string{"rust"},
+		},
+		{
+			name:      "one client",
+			durations: map[string]time.Duration{"rust": time.Second},
+			factor:    10,
+		},
+		{
+			// The budget applies to a client alone, even under the floor
+			name:      "budget alone",
+			durations: map[string]time.Duration{"rust": 30 * ms},
+			budget:    20 * ms,
+			want:      []string{"rust"},
+		},
+		{
+			name:      "budget and factor",
+			durations: map[string]time.Duration{"golang": 25 * ms, "java": 5 * ms, "rust": 600 * ms},
+			factor:    10,
+			budget:    20 * ms,
+			want:      []string{"golang", "rust"},
+		},
+		{
+			name:      "factor 0",
+			durations: map[string]time.Duration{"golang": 10 * ms, "rust": 5 * time.Second},
+		},
+		{
+			name:      "factor 0 with budget",
+			durations: map[string]time.Duration{"golang": 10 * ms, "rust": 5 * time.Second},
+			budget:    time.Second,
+			want:      []string{"rust"},
+		},
+	}
+	for _, test := range tests {
+		results := make(map[string]Result)
+		for clientName, duration := range test.durations {
+			results[clientName] = Result{Duration: duration}
+		}
+		if got := slowClients(results, test.factor, test.budget); !slices.Equal(got, test.want) {
+			t.Errorf("%s: slowClients() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMedianDuration(t *testing.T) {
+	tests := []struct {
+		durations []time.Duration
+		want      time.Duration
+	}{
+		{[]time.Duration{5}, 5},
+		{[]time.Duration{9, 1}, 5},
+		{[]time.Duration{9, 1, 4}, 4},
+		{[]time.Duration{1, 100, 2, 3}, 2},
+	}
+	for _, test := range tests {
+		durations := slices.Clone(test.durations)
+		if got := medianDuration(test.durations); got != test.want {
+			t.Errorf("medianDuration(%v) = %d, want %d", test.durations, got, test.want)
+		}
+		if !slices.Equal(durations, test.durations) {
+			t.Errorf("medianDuration reordered its argument to %v", test.durations)
+		}
+	}
+}
+
+func TestSlowSignature(t *testing.T) {
+	target := &Target{Name: "modexp", Comparator: exactComparator{}}
+	results := map[string]Result{"golang": {Output: []byte{1}}, "java": {Output: []byte{1}}}
+	vote := NewVote(target.Comparator, results)
+	got := SlowSignature(target, vote, []string{"java", "rust"}, []*Client{{Name: "rust"}})
+	if want := "target=modexp; accepted=golang,java; timed-out=rust; slow=java,rust"; got != want {
+		t.Errorf("SlowSignature() = %q, want %q", got, want)
+	}
+}
+
+func TestSlowLimits(t *testing.T) {
+	finding := &Finding{ID: "modexp-1", Slow: []string{"rust"}, SlowFactor: 10, SlowBudget: "1.5s"}
+	factor, budget, err := finding.slowLimits()
+	if err != nil || factor != 10 || budget != 1500*time.Millisecond {
+		t.Errorf("slowLimits() = %g, %s, %v, want 10, 1.5s", factor, budget, err)
+	}
+
+	// Findings saved without the limits can't be replayed
+	finding = &Finding{ID: "modexp-2", Slow: []string{"rust"}}
+	if _, _, err := finding.slowLimits(); err == nil {
+		t.Error("slowLimits() succeeded without a factor or budget")
+	}
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/attestantio/go-eth2-client/spec/altair"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/electra"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	bitfield "github.com/prysmaticlabs/go-bitfield"
+)
+
+// sszObject is implemented by every go-eth2-client SSZ type.
+type sszObject interface {
+	MarshalSSZ() ([]byte, error)
+	UnmarshalSSZ(buf []byte) error
+}
+
+// forkOrder lists the forks we have types for, oldest first.
+var forkOrder = []string{"phase0", "altair", "bellatrix", "capella", "deneb", "electra"}
+
+// sszTypes maps each fork to the objects that were introduced or changed in it.
+var sszTypes = map[string]map[string]func() sszObject{
+	"phase0": {
+		"AggregateAndProof":       func() sszObject { return &phase0.AggregateAndProof{} },
+		"Attestation":             func() sszObject { return &phase0.Attestation{} },
+		"AttestationData":         func() sszObject { return &phase0.AttestationData{} },
+		"AttesterSlashing":        func() sszObject { return &phase0.AttesterSlashing{} },
+		"BeaconBlock":             func() sszObject { return &phase0.BeaconBlock{} },
+		"BeaconBlockBody":         func() sszObject { return &phase0.BeaconBlockBody{} },
+		"BeaconBlockHeader":       func() sszObject { return &phase0.BeaconBlockHeader{} },
+		"BeaconState":             func() sszObject { return &phase0.BeaconState{} },
+		"Checkpoint":              func() sszObject { return &phase0.Checkpoint{} },
+		"Deposit":                 func() sszObject { return &phase0.Deposit{} },
+		"DepositData":             func() sszObject { return &phase0.DepositData{} },
+		"DepositMessage":          func() sszObject { return &phase0.DepositMessage{} },
+		"Eth1Data":                func() sszObject { return &phase0.ETH1Data{} },
+		"Fork":                    func() sszObject { return &phase0.Fork{} },
+		"ForkData":                func() sszObject { return &phase0.ForkData{} },
+		"IndexedAttestation":      func() sszObject { return &phase0.IndexedAttestation{} },
+		"PendingAttestation":      func() sszObject { return &phase0.PendingAttestation{} },
+		"ProposerSlashing":        func() sszObject { return &phase0.ProposerSlashing{} },
+		"SignedAggregateAndProof": func() sszObject { return &phase0.SignedAggregateAndProof{} },
+		"SignedBeaconBlock":       func() sszObject { return &phase0.SignedBeaconBlock{} },
+		"SignedBeaconBlockHeader": func() sszObject { return &phase0.SignedBeaconBlockHeader{} },
+		"SignedVoluntaryExit":     func() sszObject { return &phase0.SignedVoluntaryExit{} },
+		"SigningData":             func() sszObject { return &phase0.SigningData{} },
+		"Validator":               func() sszObject { return &phase0.Validator{} },
+		"VoluntaryExit":           func() sszObject { return &phase0.VoluntaryExit{} },
+	},
+	"altair": {
+		"BeaconBlock":                 func() sszObject { return &altair.BeaconBlock{} },
+		"BeaconBlockBody":             func() sszObject { return &altair.BeaconBlockBody{} },
+		"BeaconState":                 func() sszObject { return &altair.BeaconState{} },
+		"ContributionAndProof":        func() sszObject { return &altair.ContributionAndProof{} },
+		"SignedBeaconBlock":           func() sszObject { return &altair.SignedBeaconBlock{} },
+		"SignedContributionAndProof":  func() sszObject { return &altair.SignedContributionAndProof{} },
+		"SyncAggregate":               func() sszObject { return &altair.SyncAggregate{} },
+		"SyncAggregatorSelectionData": func() sszObject { return &altair.SyncAggregatorSelectionData{} },
+		"SyncCommittee":               func() sszObject { return &altair.SyncCommittee{} },
+		"SyncCommitteeContribution":   func() sszObject { return &altair.SyncCommitteeContribution{} },
+		"SyncCommitteeMessage":        func() sszObject { return &altair.SyncCommitteeMessage{} },
+	},
+	"bellatrix": {
+		"BeaconBlock":            func() sszObject { return &bellatrix.BeaconBlock{} },
+		"BeaconBlockBody":        func() sszObject { return &bellatrix.BeaconBlockBody{} },
+		"BeaconState":            func() sszObject { return &bellatrix.BeaconState{} },
+		"ExecutionPayload":       func() sszObject { return &bellatrix.ExecutionPayload{} },
+		"ExecutionPayloadHeader": func() sszObject { return &bellatrix.ExecutionPayloadHeader{} },
+		"SignedBeaconBlock":      func() sszObject { return &bellatrix.SignedBeaconBlock{} },
+	},
+	"capella": {
+		"BeaconBlock":                func() sszObject { return &capella.BeaconBlock{} },
+		"BeaconBlockBody":            func() sszObject { return &capella.BeaconBlockBody{} },
+		"BeaconState":                func() sszObject { return &capella.BeaconState{} },
+		"BLSToExecutionChange":       func() sszObject { return &capella.BLSToExecutionChange{} },
+		"ExecutionPayload":           func() sszObject { return &capella.ExecutionPayload{} },
+		"ExecutionPayloadHeader":     func() sszObject { return &capella.ExecutionPayloadHeader{} },
+		"HistoricalSummary":          func() sszObject { return &capella.HistoricalSummary{} },
+		"SignedBeaconBlock":          func() sszObject { return &capella.SignedBeaconBlock{} },
+		"SignedBLSToExecutionChange": func() sszObject { return &capella.SignedBLSToExecutionChange{} },
+		"Withdrawal":                 func() sszObject { return &capella.Withdrawal{} },
+	},
+	"deneb": {
+		"BeaconBlock":            func() sszObject { return &deneb.BeaconBlock{} },
+		"BeaconBlockBody":        func() sszObject { return &deneb.BeaconBlockBody{} },
+		"BeaconState":            func() sszObject { return &deneb.BeaconState{} },
+		"BlobIdentifier":         func() sszObject { return &deneb.BlobIdentifier{} },
+		"BlobSidecar":            func() sszObject { return &deneb.BlobSidecar{} },
+		"ExecutionPayload":       func() sszObject { return &deneb.ExecutionPayload{} },
+		"ExecutionPayloadHeader": func() sszObject { return &deneb.ExecutionPayloadHeader{} },
+		"SignedBeaconBlock":      func() sszObject { return &deneb.SignedBeaconBlock{} },
+	},
+	"electra": {
+		"AggregateAndProof":        func() sszObject { return &electra.AggregateAndProof{} },
+		"Attestation":              func() sszObject { return &electra.Attestation{} },
+		"AttesterSlashing":         func() sszObject { return &electra.AttesterSlashing{} },
+		"BeaconBlock":              func() sszObject { return &electra.BeaconBlock{} },
+		"BeaconBlockBody":          func() sszObject { return &electra.BeaconBlockBody{} },
+		"BeaconState":              func() sszObject { return &electra.BeaconState{} },
+		"ConsolidationRequest":     func() sszObject { return &electra.ConsolidationRequest{} },
+		"DepositRequest":           func() sszObject { return &electra.DepositRequest{} },
+		"ExecutionRequests":        func() sszObject { return &electra.ExecutionRequests{} },
+		"IndexedAttestation":       func() sszObject { return &electra.IndexedAttestation{} },
+		"PendingConsolidation":     func() sszObject { return &electra.PendingConsolidation{} },
+		"PendingDeposit":           func() sszObject { return &electra.PendingDeposit{} },
+		"PendingPartialWithdrawal": func() sszObject { return &electra.PendingPartialWithdrawal{} },
+		"SignedAggregateAndProof":  func() sszObject { return &electra.SignedAggregateAndProof{} },
+		"SignedBeaconBlock":        func() sszObject { return &electra.SignedBeaconBlock{} },
+		"SingleAttestation":        func() sszObject { return &electra.SingleAttestation{} },
+		"WithdrawalRequest":        func() sszObject { return &electra.WithdrawalRequest{} },
+	},
+}
+
+// newSSZObject returns an empty object of the given type as it is defined in the fork.
+// Objects that did not change in the fork are taken from the most recent fork that did.
+func newSSZObject(fork string, object string) (sszObject, bool) {
+	index := -1
+	for i, name := range forkOrder {
+		if name == fork {
+			index = i
+		}
+	}
+	for i := index; i >= 0; i-- {
+		if constructor, ok := sszTypes[forkOrder[i]][object]; ok {
+			return constructor(), true
+		}
+	}
+	return nil, false
+}
+
+// errNotSSZ is returned when the input cannot be handled by the SSZ mutator.
+var errNotSSZ = errors.New("input is not a known SSZ object")
+
+// Well-known values which are more likely to hit edge cases than random ones.
+var interestingUint64s = []uint64{
+	0,
+	1,
+	math.MaxUint64,     // FAR_FUTURE_EPOCH
+	math.MaxUint64 - 1, // One before FAR_FUTURE_EPOCH
+	math.MaxInt64,
+	math.MaxUint32,
+	16_000_000_000,    // EJECTION_BALANCE
+	32_000_000_000,    // MAX_EFFECTIVE_BALANCE
+	2_048_000_000_000, // MAX_EFFECTIVE_BALANCE_ELECTRA
+	1_000_000_000,     // EFFECTIVE_BALANCE_INCREMENT
+}
+
+// MutateSSZ decodes the input as the fork's typed object, mutates a few semantic
+// fields and re-encodes it, so the result is always valid SSZ. It returns an error if
+// the input cannot be decoded or the mutated object cannot be encoded.
+func MutateSSZ(fork string, object string, input []byte, seed int64) ([]byte, error) {
+	value, ok := newSSZObject(fork, object)
+	if !ok {
+		return nil, errNotSSZ
+	}
+	if err := value.UnmarshalSSZ(input); err != nil {
+		return nil, fmt.Errorf("failed to decode %s.%s: %w", fork, object, err)
+	}
+
+	random := rand.New(rand.NewSource(seed))
+	mutations := 1 + random.Intn(4)
+	for i := 0; i < mutations; i++ {
+		// A random path can end at a nil or empty value, so retry a few times
+		for attempt := 0; attempt < 8; attempt++ {
+			if mutateField(random, reflect.ValueOf(value).Elem(), sszTag{}) {
+				break
+			}
+		}
+	}
+
+	output, err := value.MarshalSSZ()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s.%s: %w", fork, object, err)
+	}
+	return output, nil
+}
+
+// sszTag holds the remaining dimensions of a field's ssz-size and ssz-max tags.
+type sszTag struct {
+	size []string
+	max  []string
+}
+
+// newSSZTag parses the SSZ tags of a struct field.
+func newSSZTag(field reflect.StructField) sszTag {
+	var tag sszTag
+	if size, ok := field.Tag.Lookup("ssz-size"); ok {
+		tag.size = strings.Split(size, ",")
+	}
+	if max, ok := field.Tag.Lookup("ssz-max"); ok {
+		tag.max = strings.Split(max, ",")
+	}
+	return tag
+}
+
+// fixedLength returns the fixed length of the outermost dimension, if there is one.
+func (t sszTag) fixedLength() (int, bool) {
+	if len(t.size) == 0 {
+		return 0, false
+	}
+	length, err := strconv.Atoi(t.size[0])
+	return length, err == nil
+}
+
+// maxLength returns the maximum length of the outermost dimension.
+func (t sszTag) maxLength() int {
+	if len(t.max) == 0 {
+		return 0
+	}
+	length, err := strconv.Atoi(t.max[0])
+	if err != nil {
+		return 0
+	}
+	return length
+}
+
+// inner returns the tag for the elements of the outermost dimension.
+func (t sszTag) inner() sszTag {
+	var inner sszTag
+	if len(t.size) > 1 {
+		inner.size = t.size[1:]
+	}
+	if len(t.max) > 1 {
+		inner.max = t.max[1:]
+	}
+	return inner
+}
+
+var (
+	bitlistType    = reflect.TypeOf(bitfield.Bitlist{})
+	bitvector4Type = reflect.TypeOf(bitfield.Bitvector4{})
+)
+
+// mutateField walks down a random path from the value and mutates what it reaches.
+// It returns false if nothing could be mutated along the chosen path.
+func mutateField(random *rand.Rand, v reflect.Value, tag sszTag) bool {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return false
+		}
+		return mutateField(random, v.Elem(), tag)
+
+	case reflect.Struct:
+		if v.NumField() == 0 {
+			return false
+		}
+		field := random.Intn(v.NumField())
+		if !v.Type().Field(field).IsExported() {
+			return false
+		}
+		return mutateField(random, v.Field(field), newSSZTag(v.Type().Field(field)))
+
+	case reflect.Array:
+		if v.Len() == 0 {
+			return false
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			mutateBytes(random, v.Slice(0, v.Len()).Bytes())
+			return true
+		}
+		return mutateField(random, v.Index(random.Intn(v.Len())), tag.inner())
+
+	case reflect.Slice:
+		return mutateSlice(random, v, tag)
+
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(mutateUint(random, v.Uint()) & uintMask(v.Type().Bits()))
+		return true
+
+	case reflect.Bool:
+		v.SetBool(!v.Bool())
+		return true
+	}
+	return false
+}
+
+// mutateSlice mutates either the length of a list or one of its elements.
+func mutateSlice(random *rand.Rand, v reflect.Value, tag sszTag) bool {
+	switch v.Type() {
+	case bitlistType:
+		return mutateBitlist(random, v, tag.maxLength())
+	case bitvector4Type:
+		if v.Len() == 0 {
+			return false
+		}
+		v.Index(0).SetUint(v.Index(0).Uint() ^ (1 << random.Intn(4)))
+		return true
+	}
+
+	_, fixed := tag.fixedLength()
+	if !fixed && random.Intn(4) == 0 {
+		return mutateLength(random, v, tag.maxLength())
+	}
+	if v.Len() == 0 {
+		return false
+	}
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		mutateBytes(random, v.Bytes())
+		return true
+	}
+	return mutateField(random, v.Index(random.Intn(v.Len())), tag.inner())
+}
+
+// mutateLength removes, duplicates or truncates list elements within the list's limit.
+func mutateLength(random *rand.Rand, v reflect.Value, max int) bool {
+	length := v.Len()
+	switch random.Intn(3) {
+	case 0:
+		// Remove a single element
+		if length == 0 {
+			return false
+		}
+		i := random.Intn(length)
+		v.Set(reflect.AppendSlice(v.Slice(0, i), v.Slice(i+1, length)))
+	case 1:
+		// Duplicate a single element
+		if length == 0 || (max > 0 && length >= max) {
+			return false
+		}
+		i := random.Intn(length)
+		element := v.Index(i)
+		grown := reflect.MakeSlice(v.Type(), 0, length+1)
+		grown = reflect.AppendSlice(grown, v.Slice(0, i+1))
+		grown = reflect.Append(grown, element)
+		grown = reflect.AppendSlice(grown, v.Slice(i+1, length))
+		v.Set(grown)
+	case 2:
+		// Truncate the list
+		if length == 0 {
+			return false
+		}
+		v.Set(v.Slice(0, random.Intn(length)))
+	}
+	return true
+}
+
+// mutateBitlist flips a bit or changes the length of a bitlist.
+func mutateBitlist(random *rand.Rand, v reflect.Value, max int) bool {
+	bits := v.Interface().(bitfield.Bitlist)
+	length := int(bits.Len())
+	if length > 0 && random.Intn(2) == 0 {
+		i := uint64(random.Intn(length))
+		bits.SetBitAt(i, !bits.BitAt(i))
+		return true
+	}
+
+	newLength := random.Intn(length + 2)
+	if max > 0 && newLength > max {
+		newLength = max
+	}
+	resized := bitfield.NewBitlist(uint64(newLength))
+	for i := 0; i < newLength && i < length; i++ {
+		resized.SetBitAt(uint64(i), bits.BitAt(uint64(i)))
+	}
+	v.Set(reflect.ValueOf(resized))
+	return true
+}
+
+// mutateBytes mutates fixed-size byte data such as roots, keys and signatures.
+func mutateBytes(random *rand.Rand, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	switch random.Intn(4) {
+	case 0:
+		data[random.Intn(len(data))] ^= 1 << random.Intn(8)
+	case 1:
+		data[random.Intn(len(data))] = byte(random.Intn(256))
+	case 2:
+		for i := range data {
+			data[i] = 0
+		}
+	case 3:
+		for i := range data {
+			data[i] = 0xff
+		}
+	}
+}
+
+// mutateUint returns a new value for an integer field.
+func mutateUint(random *rand.Rand, value uint64) uint64 {
+	switch random.Intn(5) {
+	case 0:
+		return interestingUint64s[random.Intn(len(interestingUint64s))]
+	case 1:
+		return value + uint64(1+random.Intn(16))
+	case 2:
+		return value - uint64(1+random.Intn(16))
+	case 3:
+		return value ^ (1 << random.Intn(64))
+	default:
+		return random.Uint64()
+	}
+}
+
+// uintMask returns a mask for an unsigned integer of the given width.
+func uintMask(bits int) uint64 {
+	if bits >= 64 {
+		return math.MaxUint64
+	}
+	return (1 << bits) - 1
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+)
+
+// sszDiffMaxLines is the most differing fields listed for a pair of outputs.
+const sszDiffMaxLines = 64
+
+// DiffSSZ decodes two encodings of the fork's object and lists the fields which differ,
+// e.g. "validators[1234].effective_balance: 32000000000 vs 31000000000".
+func DiffSSZ(fork string, object string, a []byte, b []byte) ([]string, error) {
+	valueA, ok := newSSZObject(fork, object)
+	if !ok {
+		return nil, errNotSSZ
+	}
+	valueB, _ := newSSZObject(fork, object)
+	if err := valueA.UnmarshalSSZ(a); err != nil {
+		return nil, fmt.Errorf("failed to decode %s.%s: %w", fork, object, err)
+	}
+	if err := valueB.UnmarshalSSZ(b); err != nil {
+		return nil, fmt.Errorf("failed to decode %s.%s: %w", fork, object, err)
+	}
+
+	var lines []string
+	diffValue(&lines, nil, reflect.ValueOf(valueA), reflect.ValueOf(valueB))
+	if len(lines) > sszDiffMaxLines {
+		lines = append(lines[:sszDiffMaxLines], "...")
+	}
+	return lines, nil
+}
+
+// diffPath is the path to a field, e.g. validators[3].effective_balance. It is only
+// formatted for fields which differ, since states have long lists of equal elements.
+type diffPath struct {
+	parent *diffPath
+	field  string // Field name, empty for a list element
+	index  int    // Index of a list element
+}
+
+// String formats the path, with a nil path being the object itself.
+func (p *diffPath) String() string {
+	if p == nil {
+		return ""
+	}
+	if p.field == "" {
+		return fmt.Sprintf("%s[%d]", p.parent, p.index)
+	}
+	if p.parent == nil {
+		return p.field
+	}
+	return p.parent.String() + "." + p.field
+}
+
+// diffValue appends a line for every field which differs between a and b. It stops
+// walking once enough lines have been collected.
+func diffValue(lines *[]string, path *diffPath, a reflect.Value, b reflect.Value) {
+	if len(*lines) > sszDiffMaxLines {
+		return
+	}
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				*lines = append(*lines, fmt.Sprintf("%s: %s vs %s", path, formatField(a), formatField(b)))
+			}
+			return
+		}
+		if stringer, ok := a.Interface().(fmt.Stringer); ok && a.Elem().Kind() != reflect.Struct {
+			// Leaf types such as uint256.Int
+			if other := b.Interface().(fmt.Stringer); stringer.String() != other.String() {
+				*lines = append(*lines, fmt.Sprintf("%s: %s vs %s", path, stringer, other))
+			}
+			return
+		}
+		diffValue(lines, path, a.Elem(), b.Elem())
+
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			field := a.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			diffValue(lines, &diffPath{parent: path, field: snakeCase(field.Name)}, a.Field(i), b.Field(i))
+		}
+
+	case reflect.Array, reflect.Slice:
+		if a.Type().Elem().Kind() == reflect.Uint8 {
+			diffBytes(lines, path, reflectSlice(a).Bytes(), reflectSlice(b).Bytes())
+			return
+		}
+		if a.Len() != b.Len() {
+			*lines = append(*lines, fmt.Sprintf("%s.length: %d vs %d", path, a.Len(), b.Len()))
+		}
+		for i := 0; i < a.Len() && i < b.Len(); i++ {
+			diffValue(lines, &diffPath{parent: path, index: i}, a.Index(i), b.Index(i))
+		}
+
+	default:
+		if a.Interface() != b.Interface() {
+			*lines = append(*lines, fmt.Sprintf("%s: %s vs %s", path, formatField(a), formatField(b)))
+		}
+	}
+}
+
+// diffBytes appends a line if byte data differs. Long data is shown from the first
+// differing byte, since a shortened prefix could be the same on both sides.
+func diffBytes(lines *[]string, path *diffPath, a []byte, b []byte) {
+	if bytes.Equal(a, b) {
+		return
+	}
+	name := path.String()
+	first := 0
+	for first < len(a) && first < len(b) && a[first] == b[first] {
+		first++
+	}
+	if len(a) > 32 || len(b) > 32 {
+		name = fmt.Sprintf("%s[%d:]", name, first)
+		a, b = a[first:], b[first:]
+	}
+	*lines = append(*lines, fmt.Sprintf("%s: %s vs %s", name, hexField(a), hexField(b)))
+}
+
+// hexField formats byte data in hex, shortening long data.
+func hexField(data []byte) string {
+	if len(data) == 0 {
+		return "0x"
+	}
+	return "0x" + traceBytes(data)
+}
+
+// formatField formats a leaf value, with integers in decimal.
+func formatField(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "nil"
+		}
+		return formatField(v.Elem())
+	case reflect.Struct:
+		return "{...}"
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// reflectSlice returns a slice over an array or slice, copying arrays which aren't addressable.
+func reflectSlice(v reflect.Value) reflect.Value {
+	if !v.CanAddr() {
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		v = copied
+	}
+	return v.Slice(0, v.Len())
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/attestantio/go-eth2-client/spec/electra"
+	"github.com/holiman/uint256"
+)
+
+// testStateWith returns the test state after applying a change to it.
+func testStateWith(t *testing.T, change func(*electra.BeaconState)) []byte {
+	t.Helper()
+	var state electra.BeaconState
+	if err := state.UnmarshalSSZ(testState(t)); err != nil {
+		t.Fatalf("failed to decode state: %v", err)
+	}
+	change(&state)
+	data, err := state.MarshalSSZ()
+	if err != nil {
+		t.Fatalf("failed to encode state: %v", err)
+	}
+	return data
+}
+
+func TestDiffSSZ(t *testing.T) {
+	a := testState(t)
+	b := testStateWith(t, func(state *electra.BeaconState) {
+		state.Validators[3].EffectiveBalance = 31_000_000_000
+		state.Balances = state.Balances[:60]
+		state.RANDAOMixes[5][0] = 0xff
+		state.ETH1DepositIndex = 9
+		state.LatestExecutionPayloadHeader.BaseFeePerGas = uint256.NewInt(8)
+	})
+
+	lines, err := DiffSSZ("electra", "BeaconState", a, b)
+	if err != nil {
+		t.Fatalf("DiffSSZ failed: %v", err)
+	}
+	want := []string{
+		"eth1_deposit_index: 0 vs 9",
+		"randao_mixes[5]: 0x",
+		"validators[3].effective_balance: 32000000000 vs 31000000000",
+		"balances.length: 64 vs 60",
+		"latest_execution_payload_header.base_fee_per_gas: 7 vs 8",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("DiffSSZ = %q, want %d lines", lines, len(want))
+	}
+	for _, prefix := range want {
+		if !slices.ContainsFunc(lines, func(line string) bool { return strings.HasPrefix(line, prefix) }) {
+			t.Errorf("DiffSSZ = %q, missing %q", lines, prefix)
+		}
+	}
+
+	if lines, err := DiffSSZ("electra", "BeaconState", a, a); err != nil || len(lines) != 0 {
+		t.Errorf("DiffSSZ of equal states = %q, %v", lines, err)
+	}
+	if _, err := DiffSSZ("electra", "BeaconState", a, a[:100]); err == nil {
+		t.Error("DiffSSZ of a truncated state succeeded")
+	}
+}
+
+func TestDiffSSZMaxLines(t *testing.T) {
+	b := testStateWith(t, func(state *electra.BeaconState) {
+		for _, validator := range state.Validators {
+			validator.Slashed = true
+			validator.ActivationEpoch = 1
+		}
+	})
+	lines, err := DiffSSZ("electra", "BeaconState", testState(t), b)
+	if err != nil {
+		t.Fatalf("DiffSSZ failed: %v", err)
+	}
+	if len(lines) != sszDiffMaxLines+1 || lines[sszDiffMaxLines] != "..." {
+		t.Errorf("DiffSSZ returned %d lines, want %d and \"...\"", len(lines), sszDiffMaxLines+1)
+	}
+}
+
+func TestDiffOutputs(t *testing.T) {
+	target := &Target{Name: "state", Fork: "electra", Comparator: exactComparator{}}
+	if err := target.SetOutput("BeaconState"); err != nil {
+		t.Fatal(err)
+	}
+	changed := testStateWith(t, func(state *electra.BeaconState) {
+		state.Validators[3].EffectiveBalance = 31_000_000_000
+		state.Slot = 1
+	})
+	results := map[string]Result{
+		"a": {Output: testState(t)},
+		"b": {Output: testState(t)},
+		"c": {Output: changed},
+	}
+	lines, fields := DiffOutputs(target, NewVote(target.Comparator, results))
+	wantLines := []string{
+		"a,b vs c:",
+		"  slot: 0 vs 1",
+		"  validators[3].effective_balance: 32000000000 vs 31000000000",
+	}
+	if !slices.Equal(lines, wantLines) {
+		t.Errorf("DiffOutputs lines = %q, want %q", lines, wantLines)
+	}
+	if want := []string{"slot", "validators"}; !slices.Equal(fields, want) {
+		t.Errorf("DiffOutputs fields = %q, want %q", fields, want)
+	}
+
+	if err := target.SetOutput("NotAnObject"); err == nil {
+		t.Error("SetOutput of an unknown object succeeded")
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"Slot":                         "slot",
+		"EffectiveBalance":             "effective_balance",
+		"ETH1Data":                     "eth1_data",
+		"ETH1DepositIndex":             "eth1_deposit_index",
+		"RANDAOMixes":                  "randao_mixes",
+		"BLSToExecutionChanges":        "bls_to_execution_changes",
+		"LatestExecutionPayloadHeader": "latest_execution_payload_header",
+		"BaseFeePerGas":                "base_fee_per_gas",
+		"ExcessBlobGas":                "excess_blob_gas",
+		"KZGCommitments":               "kzg_commitments",
+		"Pubkey":                       "pubkey",
+		"":                             "",
+	}
+	for name, want := range tests {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
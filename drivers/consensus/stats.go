@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// statsLatencySamples is how many recent durations are kept per client for percentiles.
+const statsLatencySamples = 1024
+
+// ClientStats counts a client's results.
+type ClientStats struct {
+	Executions int
+	Errors     int // Inputs the client rejected
+	Timeouts   int // Inputs the client didn't answer within the timeout
+	Crashes    int
+	latencies  []time.Duration // Recent durations, used as a ring buffer
+	next       int             // Index of the oldest duration once the buffer is full
+}
+
+// addLatency records a duration, replacing the oldest once the buffer is full.
+func (c *ClientStats) addLatency(duration time.Duration) {
+	if len(c.latencies) < statsLatencySamples {
+		c.latencies = append(c.latencies, duration)
+		return
+	}
+	c.latencies[c.next] = duration
+	c.next = (c.next + 1) % statsLatencySamples
+}
+
+// Percentile returns the recent duration below which a fraction p of durations fall.
+func (c *ClientStats) Percentile(p float64) time.Duration {
+	if len(c.latencies) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(c.latencies)
+	slices.Sort(sorted)
+	return sorted[min(int(p*float64(len(sorted))), len(sorted)-1)]
+}
+
+// StatsSnapshot is a copy of the counters at one point in time.
+type StatsSnapshot struct {
+	Iterations       int
+	TotalTime        time.Duration
+	Targets          map[string]int // Iterations per target
+	Clients          map[string]*ClientStats
+	Divergences      int            // Inputs on which the clients' results differed
+	Deviations       map[string]int // Times each client was outside the majority
+	Splits           map[string]int // Times each client was on one side of a divergence without a majority
+	Suppressed       map[string]int // Times each suppression matched
+	Nondeterministic map[string]int // Times each client's result changed when rechecked
+	Slow             map[string]int // Times each client was too slow
+}
+
+// clone copies the snapshot, so it can be read while the original is updated.
+func (s *StatsSnapshot) clone() *StatsSnapshot {
+	cloned := *s
+	cloned.Targets = maps.Clone(s.Targets)
+	cloned.Clients = make(map[string]*ClientStats)
+	for clientName, client := range s.Clients {
+		copied := *client
+		copied.latencies = slices.Clone(client.latencies)
+		cloned.Clients[clientName] = &copied
+	}
+	cloned.Deviations = maps.Clone(s.Deviations)
+	cloned.Splits = maps.Clone(s.Splits)
+	cloned.Suppressed = maps.Clone(s.Suppressed)
+	cloned.Nondeterministic = maps.Clone(s.Nondeterministic)
+	cloned.Slow = maps.Clone(s.Slow)
+	return &cloned
+}
+
+// client returns a client's counters, adding them if the client is new.
+func (s *StatsSnapshot) client(clientName string) *ClientStats {
+	client, ok := s.Clients[clientName]
+	if !ok {
+		client = &ClientStats{}
+		s.Clients[clientName] = client
+	}
+	return client
+}
+
+// Stats counts what happens while fuzzing. The counters are owned by a single goroutine,
+// which applies updates in order, so they can be recorded and read from any goroutine.
+type Stats struct {
+	updates chan func(*StatsSnapshot)
+}
+
+// NewStats starts the goroutine which owns the counters.
+func NewStats() *Stats {
+	s := &Stats{updates: make(chan func(*StatsSnapshot), 1024)}
+	go func() {
+		state := &StatsSnapshot{
+			Targets:          make(map[string]int),
+			Clients:          make(map[string]*ClientStats),
+			Deviations:       make(map[string]int),
+			Splits:           make(map[string]int),
+			Suppressed:       make(map[string]int),
+			Nondeterministic: make(map[string]int),
+			Slow:             make(map[string]int),
+		}
+		for update := range s.updates {
+			update(state)
+		}
+	}()
+	return s
+}
+
+// Snapshot returns a copy of the counters.
+func (s *Stats) Snapshot() *StatsSnapshot {
+	reply := make(chan *StatsSnapshot)
+	s.updates <- func(state *StatsSnapshot) {
+		reply <- state.clone()
+	}
+	return <-reply
+}
+
+// clientSample is what an iteration recorded about one client's result.
+type clientSample struct {
+	name     string
+	duration time.Duration
+	err      bool
+}
+
+// RecordIteration counts an input sent to the clients for a target: each client's
+// result and duration, and the clients which crashed or timed out.
+func (s *Stats) RecordIteration(target string, duration time.Duration, results map[string]Result,
+	crashed []*Client, timedOut []*Client) {
+	// Results refer to shared memory, so only keep what is counted
+	var samples []clientSample
+	for clientName, result := range results {
+		samples = append(samples, clientSample{name: clientName, duration: result.Duration, err: result.Err})
+	}
+	var crashedNames []string
+	for _, client := range crashed {
+		crashedNames = append(crashedNames, client.Name)
+	}
+	var timedOutNames []string
+	for _, client := range timedOut {
+		timedOutNames = append(timedOutNames, client.Name)
+	}
+
+	s.updates <- func(state *StatsSnapshot) {
+		state.Iterations++
+		state.TotalTime += duration
+		state.Targets[target]++
+		for _, sample := range samples {
+			client := state.client(sample.name)
+			client.Executions++
+			if sample.err {
+				client.Errors++
+			}
+			client.addLatency(sample.duration)
+		}
+		for _, clientName := range crashedNames {
+			client := state.client(clientName)
+			client.Executions++
+			client.Crashes++
+		}
+		for _, clientName := range timedOutNames {
+			client := state.client(clientName)
+			client.Executions++
+			client.Timeouts++
+		}
+	}
+}
+
+// RecordCrash counts a client crashing on an input sent to it alone, such as when a
+// crash is replayed.
+func (s *Stats) RecordCrash(clientName string) {
+	s.updates <- func(state *StatsSnapshot) {
+		client := state.client(clientName)
+		client.Executions++
+		client.Crashes++
+	}
+}
+
+// ResetIterations restarts the iteration count and time, e.g. while no client is
+// connected. The other counters are kept.
+func (s *Stats) ResetIterations() {
+	s.updates <- func(state *StatsSnapshot) {
+		state.Iterations = 0
+		state.TotalTime = 0
+	}
+}
+
+// RecordDeviations counts clients which were outside the majority or crashed.
+func (s *Stats) RecordDeviations(clientNames []string) {
+	clientNames = slices.Clone(clientNames)
+	s.updates <- func(state *StatsSnapshot) {
+		for _, clientName := range clientNames {
+			state.Deviations[clientName]++
+		}
+	}
+}
+
+// RecordDivergence counts an input on which the clients' results differed, and the
+// clients on each side of it if no group holds a majority.
+func (s *Stats) RecordDivergence(vote *Vote) {
+	var split []string
+	if len(vote.Deviants) == 0 {
+		for _, group := range vote.Groups {
+			split = append(split, group.Clients...)
+		}
+	}
+	s.updates <- func(state *StatsSnapshot) {
+		state.Divergences++
+		for _, clientName := range split {
+			state.Splits[clientName]++
+		}
+	}
+}
+
+// RecordSuppressed counts a divergence matched by a suppression.
+func (s *Stats) RecordSuppressed(name string) {
+	s.updates <- func(state *StatsSnapshot) {
+		state.Suppressed[name]++
+	}
+}
+
+// RecordNondeterministic counts a client whose result changed when rechecked.
+func (s *Stats) RecordNondeterministic(clientName string) {
+	s.updates <- func(state *StatsSnapshot) {
+		state.Nondeterministic[clientName]++
+	}
+}
+
+// RecordSlow counts clients which were too slow for an input.
+func (s *Stats) RecordSlow(clientNames []string) {
+	clientNames = slices.Clone(clientNames)
+	s.updates <- func(state *StatsSnapshot) {
+		for _, clientName := range clientNames {
+			state.Slow[clientName]++
+		}
+	}
+}
+
+// Report formats the status line, followed by a line for each client.
+func (s *StatsSnapshot) Report(connected []string, buckets int) string {
+	var b strings.Builder
+	var average time.Duration
+	if s.Iterations > 0 {
+		average = s.TotalTime / time.Duration(s.Iterations)
+	}
+	fmt.Fprintf(&b, "Fuzzing Time: %s, Iterations: %v, Average Iteration: %s, Targets: %s, Clients: %v, "+
+		"Divergences: %d, Deviations: %v, Splits: %v, Suppressed: %v, Nondeterministic: %v, Slow: %v, "+
+		"Buckets: %d\n",
+		s.TotalTime.Round(time.Second), s.Iterations, average.Round(time.Millisecond),
+		formatCounts(s.Targets), strings.Join(connected, ","), s.Divergences, formatCounts(s.Deviations),
+		formatCounts(s.Splits), formatCounts(s.Suppressed), formatCounts(s.Nondeterministic), formatCounts(s.Slow), buckets)
+
+	clientNames := slices.Collect(maps.Keys(s.Clients))
+	sort.Strings(clientNames)
+	for _, clientName := range clientNames {
+		client := s.Clients[clientName]
+		fmt.Fprintf(&b, "  Client: %s, Executions: %d, Errors: %d, Timeouts: %d, Crashes: %d, "+
+			"Latency p50: %s, p90: %s, p99: %s\n",
+			clientName, client.Executions, client.Errors, client.Timeouts, client.Crashes,
+			client.Percentile(0.5), client.Percentile(0.9), client.Percentile(0.99))
+	}
+	return b.String()
+}
+
+// formatCounts formats counts as sorted name=count pairs.
+func formatCounts(counts map[string]int) string {
+	var pairs []string
+	for name, count := range counts {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, count))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"maps"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	client := &ClientStats{}
+	if got := client.Percentile(0.5); got != 0 {
+		t.Errorf("Percentile of no durations = %s, want 0", got)
+	}
+	// Added out of order, 1ms to 100ms
+	for i := 100; i >= 1; i-- {
+		client.addLatency(time.Duration(i) * time.Millisecond)
+	}
+	tests := map[float64]time.Duration{
+		0:    1 * time.Millisecond,
+		0.5:  51 * time.Millisecond,
+		0.9:  91 * time.Millisecond,
+		0.99: 100 * time.Millisecond,
+		1:    100 * time.Millisecond,
+	}
+	for p, want := range tests {
+		if got := client.Percentile(p); got != want {
+			t.Errorf("Percentile(%v) = %s, want %s", p, got, want)
+		}
+	}
+}
+
+func TestLatencyRingBuffer(t *testing.T) {
+	client := &ClientStats{}
+	for i := 0; i < statsLatencySamples; i++ {
+		client.addLatency(time.Second)
+	}
+	// Newer durations replace the oldest ones
+	for i := 0; i < statsLatencySamples/2+1; i++ {
+		client.addLatency(time.Millisecond)
+	}
+	if len(client.latencies) != statsLatencySamples {
+		t.Errorf("kept %d durations, want %d", len(client.latencies), statsLatencySamples)
+	}
+	if got := client.Percentile(0.5); got != time.Millisecond {
+		t.Errorf("Percentile(0.5) = %s, want 1ms once most durations were replaced", got)
+	}
+	for i := 0; i < statsLatencySamples; i++ {
+		client.addLatency(2 * time.Millisecond)
+	}
+	if got := client.Percentile(1); got != 2*time.Millisecond {
+		t.Errorf("Percentile(1) = %s, want 2ms once every duration was replaced", got)
+	}
+}
+
+func TestStatsConcurrent(t *testing.T) {
+	stats := NewStats()
+	results := map[string]Result{
+		"golang": {Duration: time.Millisecond},
+		"rust":   {Duration: 2 * time.Millisecond, Err: true},
+	}
+	crashed := []*Client{{Name: "java"}}
+	timedOut := []*Client{{Name: "nim"}}
+
+	const writers, iterations = 4, 500
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				stats.RecordIteration("bn256Add", time.Millisecond, results, crashed, timedOut)
+				stats.RecordDeviations([]string{"rust"})
+				stats.RecordSlow([]string{"rust"})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations/10; j++ {
+				// Snapshots must be safe to read while the counters change
+				snapshot := stats.Snapshot()
+				snapshot.Report([]string{"golang", "rust"}, 0)
+			}
+		}()
+	}
+	wg.Wait()
+
+	snapshot := stats.Snapshot()
+	total := writers * iterations
+	if snapshot.Iterations != total || snapshot.Targets["bn256Add"] != total {
+		t.Errorf("iterations = %d, targets = %v, want %d", snapshot.Iterations, snapshot.Targets, total)
+	}
+	if rust := snapshot.Clients["rust"]; rust.Executions != total || rust.Errors != total {
+		t.Errorf("rust executions = %d, errors = %d, want %d", rust.Executions, rust.Errors, total)
+	}
+	if java := snapshot.Clients["java"]; java.Crashes != total {
+		t.Errorf("java crashes = %d, want %d", java.Crashes, total)
+	}
+	if nim := snapshot.Clients["nim"]; nim.Timeouts != total || nim.Executions != total {
+		t.Errorf("nim timeouts = %d, executions = %d, want %d", nim.Timeouts, nim.Executions, total)
+	}
+	if snapshot.Deviations["rust"] != total || snapshot.Slow["rust"] != total {
+		t.Errorf("deviations = %v, slow = %v, want %d", snapshot.Deviations, snapshot.Slow, total)
+	}
+}
+
+func TestRecordDivergence(t *testing.T) {
+	stats := NewStats()
+	comparator := exactComparator{}
+	// A majority, a two-client split and a three-way split
+	stats.RecordDivergence(NewVote(comparator, map[string]Result{
+		"golang": {Output: []byte{1}}, "java": {Output: []byte{1}}, "rust": {Output: []byte{2}},
+	}))
+	stats.RecordDivergence(NewVote(comparator, map[string]Result{
+		"golang": {Output: []byte{1}}, "rust": {Output: []byte{2}},
+	}))
+	stats.RecordDivergence(NewVote(comparator, map[string]Result{
+		"golang": {Output: []byte{1}}, "java": {Output: []byte{2}}, "rust": {Output: []byte{3}},
+	}))
+
+	snapshot := stats.Snapshot()
+	if snapshot.Divergences != 3 {
+		t.Errorf("divergences = %d, want 3", snapshot.Divergences)
+	}
+	want := map[string]int{"golang": 2, "java": 1, "rust": 2}
+	if !maps.Equal(snapshot.Splits, want) {
+		t.Errorf("splits = %v, want %v", snapshot.Splits, want)
+	}
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// logsDir is where the output of supervised processors is written, one file per client.
+	logsDir = "logs"
+
+	// crashLogMaxSize is the most output of a crashed run copied into a finding.
+	crashLogMaxSize = 64 * 1024
+
+	// crashLogTimeout is how long to wait for a crashed processor to exit before its
+	// output is copied.
+	crashLogTimeout = 5 * time.Second
+
+	minRestartDelay = 1 * time.Second
+	maxRestartDelay = 1 * time.Minute
+
+	// clockTicks is the unit of CPU times in /proc, USER_HZ, which is 100 on Linux.
+	clockTicks = 100
+
+	// cgroupRoot is where the cgroup v2 hierarchy is mounted.
+	cgroupRoot = "/sys/fs/cgroup"
+)
+
+// oomPatterns are printed by runtimes which fail to allocate memory under an address
+// space limit.
+var oomPatterns = []string{
+	"out of memory",          // Go, C
+	"memory allocation of",   // Rust
+	"OutOfMemoryError",       // Java
+	"cannot allocate memory", // errno ENOMEM
+	"std::bad_alloc",         // C++
+}
+
+// ProcessorConfig describes a processor for the driver to start and restart.
+type ProcessorConfig struct {
+	Name    string   // Client name the processor registers with
+	Command []string // Program and arguments, e.g. ["go", "run", "."]
+	Dir     string   `json:",omitempty"` // Working directory, the driver's if empty
+	Env     []string `json:",omitempty"` // KEY=VALUE pairs added to the driver's environment
+
+	// Resource limits, unlimited if zero. They apply to the whole command, so limited
+	// processors should run a built binary rather than e.g. "go run".
+	MemoryMB   int `json:",omitempty"` // A cgroup v2 memory cap if available, or else an address space limit
+	CPUSeconds int `json:",omitempty"` // CPU time of a run, which is restarted once it used half of it
+	OpenFiles  int `json:",omitempty"` // Open file descriptors
+}
+
+// LoadProcessors reads a JSON list of processors.
+func LoadProcessors(path string) ([]*ProcessorConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processors: %w", err)
+	}
+	var configs []*ProcessorConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to decode processors: %w", err)
+	}
+	names := make(map[string]bool)
+	for _, config := range configs {
+		if config.Name == "" {
+			return nil, fmt.Errorf("processor in %s has no name", path)
+		}
+		if names[config.Name] {
+			return nil, fmt.Errorf("processor %s is listed twice", config.Name)
+		}
+		names[config.Name] = true
+		if len(config.Command) == 0 {
+			return nil, fmt.Errorf("processor %s has no command", config.Name)
+		}
+		if config.MemoryMB < 0 || config.CPUSeconds < 0 || config.OpenFiles < 0 {
+			return nil, fmt.Errorf("processor %s has a negative limit", config.Name)
+		}
+	}
+	return configs, nil
+}
+
+// processRun is the output of one run of a processor in its log file.
+type processRun struct {
+	started  time.Time
+	pid      int
+	start    int64         // Offset of the run's first output in the log file
+	end      int64         // Offset after the run's last output, set once it exits
+	cause    string        // Why the run exited, set once it exits
+	cpuTime  time.Duration // CPU time of the whole run, set once it exits
+	inputCPU time.Duration // CPU time used before the last input was sent, -1 if unknown
+	exited   chan struct{} // Closed once the run exits
+}
+
+// CrashReport is why a supervised processor exited and the output of its last run.
+type CrashReport struct {
+	Cause string // One of the crash causes, e.g. "oom"
+	Log   []byte
+}
+
+// supervisedProcess is a processor started by the driver.
+type supervisedProcess struct {
+	config  *ProcessorConfig
+	logPath string
+	cgroup  string // Directory of the processor's cgroup, empty if memory is limited by rlimit
+
+	mu        sync.Mutex // Protects the fields below
+	cmd       *exec.Cmd
+	run       *processRun // The current or last run
+	previous  *processRun // The run before, which a crash may belong to once restarted
+	restarted bool        // The run was killed by Restart, so it starts again without a delay
+}
+
+// Supervisor starts processors, writes their output to log files and restarts them when
+// they exit.
+type Supervisor struct {
+	processes map[string]*supervisedProcess
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewSupervisor starts every processor in the background.
+func NewSupervisor(configs []*ProcessorConfig) (*Supervisor, error) {
+	if err := os.MkdirAll(logsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create logs directory: %w", err)
+	}
+	s := &Supervisor{
+		processes: make(map[string]*supervisedProcess),
+		stop:      make(chan struct{}),
+	}
+	for _, config := range configs {
+		process := &supervisedProcess{
+			config:  config,
+			logPath: filepath.Join(logsDir, config.Name+".log"),
+		}
+		if config.MemoryMB > 0 {
+			cgroup, err := newCgroup(config.Name, config.MemoryMB)
+			if err != nil {
+				fmt.Printf("Limiting address space of %s, cgroup v2 memory cap unavailable: %v\n", config.Name, err)
+			} else {
+				process.cgroup = cgroup
+			}
+		}
+		s.processes[config.Name] = process
+		s.wg.Add(1)
+		go s.supervise(process)
+	}
+	return s, nil
+}
+
+// Stop kills every processor and waits until they have exited.
+func (s *Supervisor) Stop() {
+	close(s.stop)
+	for _, process := range s.processes {
+		process.mu.Lock()
+		if process.cmd != nil {
+			// Kill the process group, commands like "go run" start the processor as a child
+			syscall.Kill(-process.cmd.Process.Pid, syscall.SIGKILL)
+		}
+		process.mu.Unlock()
+	}
+	s.wg.Wait()
+	for _, process := range s.processes {
+		if process.cgroup != "" {
+			os.Remove(process.cgroup)
+		}
+	}
+}
+
+// supervise runs a processor until the supervisor stops. The delay before a restart
+// doubles each time the processor exits soon after starting.
+func (s *Supervisor) supervise(process *supervisedProcess) {
+	defer s.wg.Done()
+	delay := minRestartDelay
+	for {
+		started := time.Now()
+		err := process.runOnce(s.stop)
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		process.mu.Lock()
+		restarted := process.restarted
+		process.restarted = false
+		process.mu.Unlock()
+		if restarted {
+			continue
+		}
+
+		if time.Since(started) > maxRestartDelay {
+			delay = minRestartDelay
+		}
+		fmt.Printf("Processor %s exited (%v), restarting in %s\n", process.config.Name, err, delay)
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRestartDelay)
+	}
+}
+
+// runOnce starts the processor with its output appended to its log file and waits for
+// it to exit.
+func (p *supervisedProcess) runOnce(stop chan struct{}) error {
+	logFile, err := os.OpenFile(p.logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer logFile.Close()
+	fmt.Fprintf(logFile, "--- Starting %q at %s ---\n", p.config.Command, time.Now().UTC().Format(time.RFC3339))
+	start, err := logFile.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("failed to seek log file: %w", err)
+	}
+
+	oomKills := cgroupOOMKills(p.cgroup)
+	cmd := p.command()
+	cmd.Dir = p.config.Dir
+	cmd.Env = append(os.Environ(), p.config.Env...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	// Don't start a run once the supervisor stops, it would never be killed
+	p.mu.Lock()
+	select {
+	case <-stop:
+		p.mu.Unlock()
+		return nil
+	default:
+	}
+	if err := cmd.Start(); err != nil {
+		p.mu.Unlock()
+		fmt.Fprintf(logFile, "--- Failed to start: %v ---\n", err)
+		return fmt.Errorf("failed to start: %w", err)
+	}
+	run := &processRun{started: time.Now(), pid: cmd.Process.Pid, start: start, inputCPU: -1, exited: make(chan struct{})}
+	p.cmd = cmd
+	p.previous = p.run
+	p.run = run
+	p.mu.Unlock()
+
+	err = cmd.Wait()
+	end, _ := logFile.Seek(0, io.SeekEnd)
+	cause := p.exitCause(cmd.ProcessState, cgroupOOMKills(p.cgroup) > oomKills, start, end)
+	fmt.Fprintf(logFile, "--- Exited: %v (cause: %s) ---\n", err, cause)
+
+	p.mu.Lock()
+	p.cmd = nil
+	run.end = end
+	run.cause = cause
+	run.cpuTime = cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+	close(run.exited)
+	p.mu.Unlock()
+	if err == nil {
+		err = errors.New("exit status 0")
+	}
+	return fmt.Errorf("%w, cause: %s", err, cause)
+}
+
+// command returns the processor's command, run by a shell which first applies its
+// resource limits.
+func (p *supervisedProcess) command() *exec.Cmd {
+	var script []string
+	if p.cgroup != "" {
+		// Join the cgroup before exec, so no allocation escapes the cap
+		script = append(script, fmt.Sprintf("echo $$ > '%s'", filepath.Join(p.cgroup, "cgroup.procs")))
+	} else if p.config.MemoryMB > 0 {
+		script = append(script, fmt.Sprintf("ulimit -v %d", p.config.MemoryMB*1024))
+	}
+	if p.config.CPUSeconds > 0 {
+		script = append(script, fmt.Sprintf("ulimit -t %d", p.config.CPUSeconds))
+	}
+	if p.config.OpenFiles > 0 {
+		script = append(script, fmt.Sprintf("ulimit -n %d", p.config.OpenFiles))
+	}
+	if len(script) == 0 {
+		return exec.Command(p.config.Command[0], p.config.Command[1:]...)
+	}
+	script = append(script, `exec "$@"`)
+	args := append([]string{"-c", strings.Join(script, " && "), "sh"}, p.config.Command...)
+	return exec.Command("/bin/sh", args...)
+}
+
+// exitCause classifies why a run exited from its exit status, whether its cgroup killed
+// it, and its output between two offsets of the log file. Running out of CPU time is a
+// limit on the whole run; Crash checks whether the input was to blame.
+func (p *supervisedProcess) exitCause(state *os.ProcessState, oomKilled bool, start int64, end int64) string {
+	if oomKilled {
+		return crashCauseOOM
+	}
+	if p.config.CPUSeconds > 0 {
+		// The kernel sends SIGXCPU and then SIGKILL at the limit. CPU time is sampled, so
+		// it may be reported just under the limit.
+		status, ok := state.Sys().(syscall.WaitStatus)
+		cpuTime := state.UserTime() + state.SystemTime()
+		limit := time.Duration(p.config.CPUSeconds) * time.Second
+		if (ok && status.Signaled() && status.Signal() == syscall.SIGXCPU) || cpuTime >= limit*19/20 {
+			return crashCauseCPU
+		}
+	}
+	if p.config.MemoryMB > 0 && p.cgroup == "" {
+		output, err := readLogRange(p.logPath, start, end)
+		if err == nil {
+			for _, pattern := range oomPatterns {
+				if bytes.Contains(output, []byte(pattern)) {
+					return crashCauseOOM
+				}
+			}
+		}
+	}
+	return crashCauseCrashed
+}
+
+// newCgroup creates a cgroup v2 group for a processor with its memory capped, and
+// returns its directory.
+func newCgroup(name string, memoryMB int) (string, error) {
+	controllers, err := os.ReadFile(filepath.Join(cgroupRoot, "cgroup.controllers"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read cgroup controllers: %w", err)
+	}
+	if !slices.Contains(strings.Fields(string(controllers)), "memory") {
+		return "", errors.New("memory controller is not available")
+	}
+	subtreeControl := filepath.Join(cgroupRoot, "cgroup.subtree_control")
+	if err := os.WriteFile(subtreeControl, []byte("+memory"), 0644); err != nil {
+		return "", fmt.Errorf("failed to enable memory controller: %w", err)
+	}
+
+	dir := filepath.Join(cgroupRoot, "eth-diff-fuzz-"+name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	memoryMax := strconv.Itoa(memoryMB * 1024 * 1024)
+	if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(memoryMax), 0644); err != nil {
+		os.Remove(dir)
+		return "", fmt.Errorf("failed to set memory cap: %w", err)
+	}
+	// Don't let the processor swap instead of being killed, if swap is accounted
+	os.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0644)
+	return dir, nil
+}
+
+// cgroupOOMKills returns how many processes the kernel killed in a cgroup for exceeding
+// its memory cap, or zero if there is no cgroup.
+func cgroupOOMKills(dir string) int {
+	if dir == "" {
+		return 0
+	}
+	events, err := os.ReadFile(filepath.Join(dir, "memory.events"))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(events), "\n") {
+		if count, ok := strings.CutPrefix(line, "oom_kill "); ok {
+			kills, _ := strconv.Atoi(count)
+			return kills
+		}
+	}
+	return 0
+}
+
+// Spent returns the clients whose processors used half of their CPU time limit. They
+// should be restarted before the next input, so running out of CPU time means that
+// input used most of the limit.
+func (s *Supervisor) Spent() []string {
+	var spent []string
+	for clientName, process := range s.processes {
+		if process.config.CPUSeconds == 0 {
+			continue
+		}
+		process.mu.Lock()
+		run := process.run
+		running := process.cmd != nil
+		process.mu.Unlock()
+		if !running {
+			continue
+		}
+		cpuTime, err := processCPUTime(run.pid)
+		if err == nil && cpuTime >= time.Duration(process.config.CPUSeconds)*time.Second/2 {
+			spent = append(spent, clientName)
+		}
+	}
+	slices.Sort(spent)
+	return spent
+}
+
+// Restart kills a client's processor so it is started again without a delay.
+func (s *Supervisor) Restart(clientName string) {
+	process, ok := s.processes[clientName]
+	if !ok {
+		return
+	}
+	process.mu.Lock()
+	defer process.mu.Unlock()
+	if process.cmd != nil {
+		process.restarted = true
+		syscall.Kill(-process.cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// MarkInput records the CPU time each processor used before an input is sent, so a
+// crash can be blamed on the CPU time of the input.
+func (s *Supervisor) MarkInput() {
+	for _, process := range s.processes {
+		if process.config.CPUSeconds == 0 {
+			continue
+		}
+		process.mu.Lock()
+		run := process.run
+		running := process.cmd != nil
+		process.mu.Unlock()
+		if !running {
+			continue
+		}
+		cpuTime, err := processCPUTime(run.pid)
+		if err != nil {
+			cpuTime = -1
+		}
+		process.mu.Lock()
+		run.inputCPU = cpuTime
+		process.mu.Unlock()
+	}
+}
+
+// processCPUTime returns the user and system CPU time a running process used so far.
+func processCPUTime(pid int) (time.Duration, error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	// The command name may hold spaces, so fields are counted after it. utime and stime
+	// are the 14th and 15th fields, the 3rd and 4th are the first after the name.
+	end := bytes.LastIndexByte(stat, ')')
+	if end < 0 {
+		return 0, fmt.Errorf("malformed stat of process %d", pid)
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("malformed stat of process %d", pid)
+	}
+	utime, err := strconv.ParseInt(fields[11], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed stat of process %d: %w", pid, err)
+	}
+	stime, err := strconv.ParseInt(fields[12], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed stat of process %d: %w", pid, err)
+	}
+	return time.Duration(utime+stime) * time.Second / clockTicks, nil
+}
+
+// Crash waits for a client's processor to exit and reports why it exited, with the end
+// of its output from the run which was sent the input at the given time. Running out of
+// CPU time is only blamed on the input if the input used most of the limit. It returns
+// false if the client isn't supervised.
+func (s *Supervisor) Crash(clientName string, sent time.Time) (*CrashReport, bool) {
+	process, ok := s.processes[clientName]
+	if !ok {
+		return nil, false
+	}
+	process.mu.Lock()
+	run := process.run
+	if run != nil && run.started.After(sent) {
+		// Restarted while other processors were still processing the input
+		run = process.previous
+	}
+	process.mu.Unlock()
+	if run == nil {
+		return nil, false
+	}
+
+	// A processor may close its connection before exiting, so give it time to finish
+	report := &CrashReport{Cause: crashCauseCrashed}
+	end := int64(-1)
+	select {
+	case <-run.exited:
+		process.mu.Lock()
+		end = run.end
+		report.Cause = run.cause
+		limit := time.Duration(process.config.CPUSeconds) * time.Second
+		if report.Cause == crashCauseCPU && run.inputCPU >= 0 && run.cpuTime-run.inputCPU < limit/2 {
+			// The run used up its limit on earlier inputs
+			report.Cause = crashCauseCrashed
+		}
+		process.mu.Unlock()
+	case <-time.After(crashLogTimeout):
+	}
+
+	var err error
+	report.Log, err = readLogRange(process.logPath, run.start, end)
+	if err != nil {
+		fmt.Printf("Error reading log of %s: %v\n", clientName, err)
+	}
+	return report, true
+}
+
+// readLogRange reads the last crashLogMaxSize bytes of a log file between two offsets,
+// or up to the end of the file if end is negative.
+func readLogRange(path string, start int64, end int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if end < 0 {
+		end, err = file.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+	}
+	start = max(start, end-crashLogMaxSize)
+	data := make([]byte, end-start)
+	if _, err := file.ReadAt(data, start); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data, nil
+}
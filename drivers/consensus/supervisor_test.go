@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestProcessCPUTime(t *testing.T) {
+	before, err := processCPUTime(os.Getpid())
+	if err != nil {
+		t.Fatalf("processCPUTime failed: %v", err)
+	}
+	for start := time.Now(); time.Since(start) < 100*time.Millisecond; {
+	}
+	after, err := processCPUTime(os.Getpid())
+	if err != nil {
+		t.Fatalf("processCPUTime failed: %v", err)
+	}
+	// The spin is sampled in clock ticks of 10ms
+	if used := after - before; used < 50*time.Millisecond || used > 10*time.Second {
+		t.Errorf("processCPUTime counted %s for spinning 100ms", used)
+	}
+	if _, err := processCPUTime(-1); err == nil {
+		t.Error("processCPUTime of a missing process succeeded")
+	}
+}
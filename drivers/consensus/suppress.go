@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+)
+
+// Suppression is a rule for a known divergence. Findings which match every field that
+// is set are counted instead of reported.
+type Suppression struct {
+	Name       string            // Shown in the status line, e.g. an upstream issue
+	Target     string            `json:",omitempty"`
+	Deviant    string            `json:",omitempty"` // Client which must be outside the majority
+	Statuses   map[string]string `json:",omitempty"` // Client to "accepted" or "rejected"
+	ErrorClass string            `json:",omitempty"` // Class of a rejecting client's error
+	Error      string            `json:",omitempty"` // Pattern matched against a rejecting client's error
+	Outputs    []string          `json:",omitempty"` // Hex sha256 of every differing output
+
+	errorPattern *regexp.Regexp
+}
+
+// LoadSuppressions reads a JSON list of suppression rules.
+func LoadSuppressions(path string) ([]*Suppression, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suppressions: %w", err)
+	}
+	var suppressions []*Suppression
+	if err := json.Unmarshal(data, &suppressions); err != nil {
+		return nil, fmt.Errorf("failed to decode suppressions: %w", err)
+	}
+	for _, suppression := range suppressions {
+		if suppression.Name == "" {
+			return nil, fmt.Errorf("suppression in %s has no name", path)
+		}
+		for client, status := range suppression.Statuses {
+			if status != "accepted" && status != "rejected" {
+				return nil, fmt.Errorf("suppression %s has invalid status %q for %s",
+					suppression.Name, status, client)
+			}
+		}
+		if suppression.Error != "" {
+			suppression.errorPattern, err = regexp.Compile(suppression.Error)
+			if err != nil {
+				return nil, fmt.Errorf("suppression %s has invalid error pattern: %w", suppression.Name, err)
+			}
+		}
+	}
+	return suppressions, nil
+}
+
+// matchSuppression returns the first rule matching a divergence, or nil if there is none.
+func matchSuppression(suppressions []*Suppression, target string, vote *Vote, results map[string]Result) *Suppression {
+	for _, suppression := range suppressions {
+		if suppression.Matches(target, vote, results) {
+			return suppression
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the divergence matches every field of the rule that is set.
+// Errors and outputs are checked for each client, since a group's clients may word
+// their errors differently.
+func (s *Suppression) Matches(target string, vote *Vote, results map[string]Result) bool {
+	if s.Target != "" && s.Target != target {
+		return false
+	}
+	if s.Deviant != "" && !slices.Contains(vote.Deviants, s.Deviant) {
+		return false
+	}
+	for client, status := range s.Statuses {
+		result, ok := results[client]
+		if !ok || status != resultStatus(result) {
+			return false
+		}
+	}
+	if s.ErrorClass != "" || s.errorPattern != nil {
+		matched := false
+		for _, result := range results {
+			message := string(result.Output)
+			if result.Err &&
+				(s.ErrorClass == "" || s.ErrorClass == ErrorClass(message)) &&
+				(s.errorPattern == nil || s.errorPattern.MatchString(message)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(s.Outputs) > 0 {
+		for _, result := range results {
+			hash := sha256.Sum256(result.Output)
+			if !slices.Contains(s.Outputs, hex.EncodeToString(hash[:])) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// resultStatus returns "accepted" or "rejected".
+func resultStatus(result Result) string {
+	if result.Err {
+		return "rejected"
+	}
+	return "accepted"
+}
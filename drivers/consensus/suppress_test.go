@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testSuppression loads a single rule, so its error pattern is compiled.
+func testSuppression(t *testing.T, rule string) *Suppression {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "suppressions.json")
+	if err := os.WriteFile(path, []byte("["+rule+"]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	suppressions, err := LoadSuppressions(path)
+	if err != nil {
+		t.Fatalf("LoadSuppressions(%s) failed: %v", rule, err)
+	}
+	return suppressions[0]
+}
+
+func TestSuppressionMatches(t *testing.T) {
+	// geth and besu reject the input with differently worded length errors, reth accepts it
+	results := map[string]Result{
+		"besu": {Output: []byte("Invalid input length"), Err: true},
+		"geth": {Output: []byte("bn256: coordinate exceeds modulus"), Err: true},
+		"reth": {Output: []byte{1, 2, 3}},
+	}
+	vote := NewVote(statusComparator{}, results)
+	hashes := make(map[string]string)
+	for clientName, result := range results {
+		hash := sha256.Sum256(result.Output)
+		hashes[clientName] = `"` + hex.EncodeToString(hash[:]) + `"`
+	}
+
+	tests := []struct {
+		rule string
+		want bool
+	}{
+		{`{"Name": "any"}`, true},
+		{`{"Name": "target", "Target": "bn256Add"}`, true},
+		{`{"Name": "other target", "Target": "bn256Mul"}`, false},
+		{`{"Name": "deviant", "Deviant": "reth"}`, true},
+		{`{"Name": "majority", "Deviant": "geth"}`, false},
+		{`{"Name": "statuses", "Statuses": {"geth": "rejected", "reth": "accepted"}}`, true},
+		{`{"Name": "wrong status", "Statuses": {"geth": "accepted"}}`, false},
+		{`{"Name": "missing client", "Statuses": {"nethermind": "rejected"}}`, false},
+		// Each client's error is checked, not only the first client's of its group
+		{`{"Name": "first client's class", "ErrorClass": "length"}`, true},
+		{`{"Name": "second client's class", "ErrorClass": "field"}`, true},
+		{`{"Name": "no class", "ErrorClass": "curve"}`, false},
+		{`{"Name": "first client's error", "Error": "(?i)invalid input"}`, true},
+		{`{"Name": "second client's error", "Error": "^bn256: "}`, true},
+		{`{"Name": "no error", "Error": "subgroup"}`, false},
+		{`{"Name": "class and error of different clients", "ErrorClass": "length", "Error": "modulus"}`, false},
+		{`{"Name": "outputs", "Outputs": [` + hashes["besu"] + `,` + hashes["geth"] + `,` + hashes["reth"] + `]}`, true},
+		{`{"Name": "missing output", "Outputs": [` + hashes["geth"] + `,` + hashes["reth"] + `]}`, false},
+		{`{"Name": "all fields", "Target": "bn256Add", "Deviant": "reth", "Statuses": {"besu": "rejected"},
+			"ErrorClass": "length", "Error": "input"}`, true},
+	}
+	for _, test := range tests {
+		suppression := testSuppression(t, test.rule)
+		if got := suppression.Matches("bn256Add", vote, results); got != test.want {
+			t.Errorf("%s: Matches() = %v, want %v", suppression.Name, got, test.want)
+		}
+	}
+}
+
+func TestLoadSuppressionsInvalid(t *testing.T) {
+	for _, rule := range []string{
+		`{"Target": "sha"}`,
+		`{"Name": "status", "Statuses": {"geth": "crashed"}}`,
+		`{"Name": "pattern", "Error": "("}`,
+	} {
+		path := filepath.Join(t.TempDir(), "suppressions.json")
+		if err := os.WriteFile(path, []byte("["+rule+"]"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSuppressions(path); err == nil {
+			t.Errorf("LoadSuppressions(%s) succeeded", rule)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Target describes a method the processors run and where its inputs come from.
+type Target struct {
+	Name       string          // Method sent to processors on registration
+	Fork       string          // Corpus fork directory, empty if there is no corpus
+	Object     string          // Corpus object directory
+	Generator  Generator       // Builds typed inputs, nil if the target only takes corpus inputs
+	Dictionary Dictionary      // Built-in tokens for the dictionary mutator
+	Mutators   []MutatorWeight // Mutators picked for corpus entries unless -mutator is set
+	Comparator Comparator      // Decides whether processor results are equivalent
+	Output     string          // Object type of outputs in the fork, diffed by field if set
+}
+
+// consensusMutators favor structure-aware mutation of SSZ objects.
+var consensusMutators = []MutatorWeight{
+	{Name: "ssz", Weight: 4},
+	{Name: "offsets", Weight: 2},
+	{Name: "dict", Weight: 1},
+	{Name: "havoc", Weight: 1},
+}
+
+// executionMutators work on raw precompile inputs.
+var executionMutators = []MutatorWeight{
+	{Name: "bytes", Weight: 2},
+	{Name: "dict", Weight: 2},
+	{Name: "havoc", Weight: 1},
+}
+
+// targets maps target names to their definitions.
+var targets = map[string]*Target{
+	"sha": {
+		Name:       "sha",
+		Fork:       "electra",
+		Object:     "BeaconState",
+		Dictionary: consensusDictionary,
+		Mutators:   consensusMutators,
+		Comparator: exactComparator{},
+	},
+	"shuffle": {
+		Name:       "shuffle",
+		Generator:  typedGenerator[ShuffleArgs](encodeShuffle),
+		Dictionary: consensusDictionary,
+		Comparator: exactComparator{},
+	},
+}
+
+func init() {
+	// Every precompile we import vectors for is also a target
+	for _, method := range precompileVectorFiles {
+		if _, exists := targets[method]; !exists {
+			targets[method] = &Target{
+				Name:       method,
+				Fork:       executionFork,
+				Object:     method,
+				Generator:  precompileGenerator(method),
+				Dictionary: executionDictionary,
+				Mutators:   executionMutators,
+				Comparator: errorClassComparator{},
+			}
+		}
+	}
+}
+
+// IsExecution returns true if the target's inputs come from the execution corpus.
+func (t *Target) IsExecution() bool {
+	return t.Fork == executionFork
+}
+
+// HasCorpus returns true if the target's inputs can come from the corpus.
+func (t *Target) HasCorpus() bool {
+	return t.Fork != ""
+}
+
+// SetOutput makes the target diff outputs field by field as an object of its fork, e.g.
+// BeaconState for processors which return the post-state.
+func (t *Target) SetOutput(object string) error {
+	if _, ok := newSSZObject(t.Fork, object); !ok {
+		return fmt.Errorf("unknown output object %q in fork %q", object, t.Fork)
+	}
+	t.Output = object
+	return nil
+}
+
+// lookupTarget returns the target with the given name.
+func lookupTarget(name string) (*Target, error) {
+	target, ok := targets[name]
+	if !ok {
+		var names []string
+		for name := range targets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown target %q (known: %v)", name, names)
+	}
+	return target, nil
+}
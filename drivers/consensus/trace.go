@@ -0,0 +1,181 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// traceMaxLines is the most edits shown when a trace is printed.
+	traceMaxLines = 64
+	// traceMaxSpan is the longest span of edited bytes a trace keeps by default. Longer
+	// spans, such as a splice or a re-encoded state, are recorded as their length and hash.
+	traceMaxSpan = 64
+)
+
+// traceData is raw bytes which are encoded as hex in JSON.
+type traceData []byte
+
+func (d traceData) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(d)), nil
+}
+
+func (d *traceData) UnmarshalText(text []byte) error {
+	data, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+	*d = data
+	return nil
+}
+
+// Edit is a single change a mutator made: Old is replaced by New at the offset. The
+// offset is a position in the data after all previous edits, so applying a trace's
+// edits in order to the corpus entry reproduces the input. Spans which are too long
+// to keep are described by OldSpan or NewSpan instead, and a splice by its Source.
+type Edit struct {
+	Mutator string       `json:"mutator"`
+	Kind    string       `json:"kind"`
+	Offset  int          `json:"offset"`
+	Old     traceData    `json:"old,omitempty"`
+	New     traceData    `json:"new,omitempty"`
+	OldSpan *traceSpan   `json:"old_span,omitempty"`
+	NewSpan *traceSpan   `json:"new_span,omitempty"`
+	Source  *traceSource `json:"source,omitempty"`
+}
+
+// traceSpan describes edited bytes which were too long to keep.
+type traceSpan struct {
+	Length int       `json:"length"`
+	SHA256 traceData `json:"sha256"`
+}
+
+// traceSource is the part of another corpus entry which a splice copied.
+type traceSource struct {
+	Path   string `json:"path"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+}
+
+// Trace records the edits made to a corpus entry. Mutators take a *Trace which may
+// be nil, in which case nothing is recorded.
+type Trace struct {
+	Mutator string // Mutator which is currently running
+	Source  string // Corpus entry which splices copy from, if known
+	MaxSpan int    // Longest span of bytes kept in an edit, traceMaxSpan if 0
+	Edits   []Edit
+}
+
+// setMutator sets the mutator which later edits are attributed to.
+func (t *Trace) setMutator(name string) {
+	if t != nil {
+		t.Mutator = name
+	}
+}
+
+// setSource sets the corpus entry which later splices copy from.
+func (t *Trace) setSource(path string) {
+	if t != nil {
+		t.Source = path
+	}
+}
+
+// add records an edit, copying the bytes since mutators reuse their buffers.
+func (t *Trace) add(kind string, offset int, old []byte, new []byte) {
+	if t == nil {
+		return
+	}
+	edit := Edit{Mutator: t.Mutator, Kind: kind, Offset: offset}
+	edit.Old, edit.OldSpan = t.span(old)
+	edit.New, edit.NewSpan = t.span(new)
+	t.Edits = append(t.Edits, edit)
+}
+
+// addSplice records that the data from the offset on was replaced by the same part
+// of the source entry. The copied bytes are only kept if they are short.
+func (t *Trace) addSplice(kind string, offset int, old []byte, new []byte) {
+	if t == nil {
+		return
+	}
+	edit := Edit{Mutator: t.Mutator, Kind: kind, Offset: offset}
+	edit.Old, edit.OldSpan = t.span(old)
+	edit.New, _ = t.span(new)
+	edit.Source = &traceSource{Path: t.Source, Offset: offset, Length: len(new)}
+	t.Edits = append(t.Edits, edit)
+}
+
+// span returns a copy of the bytes if they are short enough to keep, and their
+// length and hash otherwise.
+func (t *Trace) span(data []byte) (traceData, *traceSpan) {
+	maxSpan := t.MaxSpan
+	if maxSpan == 0 {
+		maxSpan = traceMaxSpan
+	}
+	if len(data) <= maxSpan {
+		return append(traceData(nil), data...), nil
+	}
+	hash := sha256.Sum256(data)
+	return nil, &traceSpan{Length: len(data), SHA256: hash[:]}
+}
+
+// addDiff records the span between the common prefix and suffix of the data before
+// and after a mutator which doesn't track its own edits.
+func (t *Trace) addDiff(kind string, before []byte, after []byte) {
+	if t == nil {
+		return
+	}
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+	if prefix == len(before) && prefix == len(after) {
+		return
+	}
+	t.add(kind, prefix, before[prefix:len(before)-suffix], after[prefix:len(after)-suffix])
+}
+
+// String lists the edits, one per line.
+func (t *Trace) String() string {
+	var b strings.Builder
+	for i, edit := range t.Edits {
+		if i == traceMaxLines {
+			fmt.Fprintf(&b, "... %d more edits\n", len(t.Edits)-i)
+			break
+		}
+		oldBytes, newBytes := traceBytes(edit.Old), traceBytes(edit.New)
+		if edit.OldSpan != nil {
+			oldBytes = edit.OldSpan.String()
+		}
+		if edit.NewSpan != nil {
+			newBytes = edit.NewSpan.String()
+		}
+		if edit.Source != nil {
+			newBytes = fmt.Sprintf("%d bytes of %s", edit.Source.Length, edit.Source.Path)
+		}
+		fmt.Fprintf(&b, "[%s] %s at %d: %s -> %s\n", edit.Mutator, edit.Kind, edit.Offset, oldBytes, newBytes)
+	}
+	return b.String()
+}
+
+// String formats the span as the start of its hash and its length.
+func (s *traceSpan) String() string {
+	return fmt.Sprintf("sha256:%x...(%d bytes)", s.SHA256[:8], s.Length)
+}
+
+// traceBytes formats edited bytes, shortening long spans.
+func traceBytes(data []byte) string {
+	if len(data) == 0 {
+		return "-"
+	}
+	if len(data) > 32 {
+		return fmt.Sprintf("%x...(%d bytes)", data[:32], len(data))
+	}
+	return hex.EncodeToString(data)
+}
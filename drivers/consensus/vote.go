@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ResultGroup is a set of clients whose results are equivalent.
+type ResultGroup struct {
+	Clients []string // Sorted client names
+	Result  Result   // Result of the first client
+}
+
+// Vote partitions client results into groups of equivalent results.
+type Vote struct {
+	Groups   []ResultGroup // Largest group first
+	Deviants []string      // Clients outside the majority, empty if there is none
+}
+
+// NewVote groups results by their comparator key. The largest group is the majority if
+// it holds more than half of the clients, otherwise the vote is a split.
+func NewVote(comparator Comparator, results map[string]Result) *Vote {
+	var clientNames []string
+	for client := range results {
+		clientNames = append(clientNames, client)
+	}
+	sort.Strings(clientNames)
+
+	vote := &Vote{}
+	groups := make(map[string]int)
+	for _, client := range clientNames {
+		key := string(comparator.Key(results[client]))
+		index, exists := groups[key]
+		if !exists {
+			index = len(vote.Groups)
+			groups[key] = index
+			vote.Groups = append(vote.Groups, ResultGroup{Result: results[client]})
+		}
+		vote.Groups[index].Clients = append(vote.Groups[index].Clients, client)
+	}
+	sort.SliceStable(vote.Groups, func(i, j int) bool {
+		return len(vote.Groups[i].Clients) > len(vote.Groups[j].Clients)
+	})
+
+	if vote.HasMajority() {
+		for _, group := range vote.Groups[1:] {
+			vote.Deviants = append(vote.Deviants, group.Clients...)
+		}
+		sort.Strings(vote.Deviants)
+	}
+	return vote
+}
+
+// Agreed returns true if every client returned an equivalent result.
+func (v *Vote) Agreed() bool {
+	return len(v.Groups) <= 1
+}
+
+// HasMajority returns true if more than half of the clients agree.
+func (v *Vote) HasMajority() bool {
+	total := 0
+	for _, group := range v.Groups {
+		total += len(group.Clients)
+	}
+	return len(v.Groups) > 0 && len(v.Groups[0].Clients)*2 > total
+}
+
+// String describes the outcome and lists each group's result.
+func (v *Vote) String() string {
+	var b strings.Builder
+	if len(v.Deviants) > 0 {
+		fmt.Fprintf(&b, "Deviant clients: %s (majority: %s)\n",
+			strings.Join(v.Deviants, ","), strings.Join(v.Groups[0].Clients, ","))
+	} else {
+		fmt.Fprintf(&b, "Split between %d groups, no majority\n", len(v.Groups))
+	}
+	for _, group := range v.Groups {
+		fmt.Fprintf(&b, "Clients: %s, Value: %v\n", strings.Join(group.Clients, ","), group.Result)
+	}
+	return b.String()
+}
+
+// group returns the group holding a client's result, or nil if the client has none.
+func (v *Vote) group(client string) *ResultGroup {
+	for i := range v.Groups {
+		if slices.Contains(v.Groups[i].Clients, client) {
+			return &v.Groups[i]
+		}
+	}
+	return nil
+}
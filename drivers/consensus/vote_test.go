@@ -0,0 +1,94 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewVote(t *testing.T) {
+	accepted := func(output string) Result { return Result{Output: []byte(output)} }
+	rejected := func(message string) Result { return Result{Output: []byte(message), Err: true} }
+
+	tests := []struct {
+		name     string
+		results  map[string]Result
+		groups   [][]string
+		deviants []string
+	}{
+		{
+			name:    "agreed",
+			results: map[string]Result{"b": accepted("x"), "a": accepted("x"), "c": accepted("x")},
+			groups:  [][]string{{"a", "b", "c"}},
+		},
+		{
+			name:     "one deviant",
+			results:  map[string]Result{"a": accepted("y"), "b": accepted("x"), "c": accepted("x")},
+			groups:   [][]string{{"b", "c"}, {"a"}},
+			deviants: []string{"a"},
+		},
+		{
+			name: "deviants in several groups",
+			results: map[string]Result{"a": accepted("x"), "b": rejected("bad"), "c": accepted("x"),
+				"d": accepted("z"), "e": accepted("x")},
+			groups:   [][]string{{"a", "c", "e"}, {"b"}, {"d"}},
+			deviants: []string{"b", "d"},
+		},
+		{
+			// Equal groups keep the order of their first client, and neither is the majority
+			name:    "tie",
+			results: map[string]Result{"d": accepted("x"), "c": accepted("y"), "b": accepted("x"), "a": accepted("y")},
+			groups:  [][]string{{"a", "c"}, {"b", "d"}},
+		},
+		{
+			name:    "split",
+			results: map[string]Result{"a": accepted("x"), "b": accepted("y"), "c": accepted("z")},
+			groups:  [][]string{{"a"}, {"b"}, {"c"}},
+		},
+		{
+			name:    "accepted and rejected with the same bytes",
+			results: map[string]Result{"a": accepted("x"), "b": rejected("x")},
+			groups:  [][]string{{"a"}, {"b"}},
+		},
+		{
+			name:    "no results",
+			results: map[string]Result{},
+		},
+	}
+	for _, test := range tests {
+		vote := NewVote(exactComparator{}, test.results)
+		var groups [][]string
+		for _, group := range vote.Groups {
+			groups = append(groups, group.Clients)
+			if string(group.Result.Output) != string(test.results[group.Clients[0]].Output) {
+				t.Errorf("%s: group %v has the result of another client", test.name, group.Clients)
+			}
+		}
+		if !slices.EqualFunc(groups, test.groups, slices.Equal) {
+			t.Errorf("%s: groups = %v, want %v", test.name, groups, test.groups)
+		}
+		if !slices.Equal(vote.Deviants, test.deviants) {
+			t.Errorf("%s: deviants = %v, want %v", test.name, vote.Deviants, test.deviants)
+		}
+		if got, want := vote.HasMajority(), len(test.deviants) > 0 || len(test.groups) == 1; got != want {
+			t.Errorf("%s: HasMajority() = %v, want %v", test.name, got, want)
+		}
+		if got, want := vote.Agreed(), len(test.groups) <= 1; got != want {
+			t.Errorf("%s: Agreed() = %v, want %v", test.name, got, want)
+		}
+	}
+}
+
+func TestNewVoteComparator(t *testing.T) {
+	results := map[string]Result{
+		"a": {Output: []byte("invalid input length"), Err: true},
+		"b": {Output: []byte("InvalidInputLength"), Err: true},
+		"c": {Output: []byte("point is not on curve"), Err: true},
+	}
+	vote := NewVote(errorClassComparator{}, results)
+	if !slices.Equal(vote.Deviants, []string{"c"}) {
+		t.Errorf("deviants = %v, want [c]", vote.Deviants)
+	}
+	if vote := NewVote(statusComparator{}, results); !vote.Agreed() {
+		t.Errorf("status comparator split rejections: %v", vote)
+	}
+}
@@ -11,12 +11,16 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/ethereum/go-ethereum/version"
 	"github.com/gen2brain/shm"
 
 	"github.com/jtraglia/eth-diff-fuzz/processors/golang/types"
 	"github.com/jtraglia/eth-diff-fuzz/processors/golang/execution/precompiles"
 )
 
+// responseErrorFlag is set in the response size when the output is an error message.
+const responseErrorFlag = 1 << 31
+
 func processInput(method string, input []byte, is_execution bool, geth *types.Geth) ([]byte, error) {
 	if is_execution {
 		// Handle precompile call
@@ -49,8 +53,9 @@ func main() {
 	}
 	defer stream.Close()
 
-	// Send client name
-	_, err = stream.Write([]byte("golang"))
+	// Send client name and the version of the client under test
+	name := fmt.Sprintf("golang@geth-%d.%d.%d", version.Major, version.Minor, version.Patch)
+	_, err = stream.Write([]byte(name))
 	if err != nil {
 		log.Fatalf("Failed to send name to driver: %v", err)
 	}
@@ -128,9 +133,11 @@ func main() {
 			// [@todo nethoxa] is_execution = true for testing, consensus later
 			result, err := processInput(method, inputShm[:inputSize], true, &geth)
 
-			// Write result to output
+			// Write result to output, or the error message if the input was rejected
+			responseSize := uint32(len(result))
 			if err != nil {
 				copy(outputShm[:len(err.Error())], []byte(err.Error()))
+				responseSize = uint32(len(err.Error())) | responseErrorFlag
 			} else {
 				copy(outputShm[:len(result)], result)
 			}
@@ -139,7 +146,7 @@ func main() {
 
 			// Send the size of the processed data back to the driver
 			var responseSizeBuffer [4]byte
-			binary.BigEndian.PutUint32(responseSizeBuffer[:], uint32(len(result)))
+			binary.BigEndian.PutUint32(responseSizeBuffer[:], responseSize)
 			_, err = stream.Write(responseSizeBuffer[:])
 			if err != nil {
 				fmt.Printf("Failed to send response to driver: %v\n", err)
@@ -12,6 +12,9 @@ import java.security.NoSuchAlgorithmException;
 import java.util.concurrent.atomic.AtomicBoolean;
 
 public class Main {
+    // Set in the response size when the output is an error message
+    private static final int RESPONSE_ERROR_FLAG = 1 << 31;
+
     private static byte[] processInput(String method, byte[] input)
             throws Exception, NoSuchAlgorithmException {
         switch (method) {
@@ -74,8 +77,8 @@ public class Main {
         AtomicBoolean running = new AtomicBoolean(true);
 
         try (SocketChannel socketChannel = connectToUnixSocket("/tmp/eth-cl-fuzz")) {
-            // Send client name
-            ByteBuffer nameBuffer = ByteBuffer.wrap("java".getBytes());
+            // Send client name and version
+            ByteBuffer nameBuffer = ByteBuffer.wrap(("java@" + System.getProperty("java.version")).getBytes());
             socketChannel.write(nameBuffer);
 
             // Attach to input shared memory
@@ -113,7 +116,15 @@ public class Main {
 
                 // Process the input
                 long startTime = System.nanoTime();
-                byte[] result = processInput(method, input);
+                byte[] result;
+                int flag = 0;
+                try {
+                    result = processInput(method, input);
+                } catch (Exception e) {
+                    // Rejected inputs return the error message with the error flag set
+                    result = String.valueOf(e.getMessage()).getBytes(StandardCharsets.UTF_8);
+                    flag = RESPONSE_ERROR_FLAG;
+                }
 
                 // Write result to output buffer
                 outputShm.shmAddr().getByteBuffer(0, result.length).put(result);
@@ -122,7 +133,7 @@ public class Main {
                 System.out.printf("Processing time: %.2fms%n", duration / 1_000_000.0);
 
                 // Send response size back
-                sendIntToDriver(socketChannel, result.length);
+                sendIntToDriver(socketChannel, result.length | flag);
             }
         } catch (Exception e) {
             e.printStackTrace();
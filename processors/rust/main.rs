@@ -15,6 +15,12 @@ mod execution;
 
 const SOCKET_NAME: &str = "/tmp/eth-cl-fuzz";
 
+// Version of the client under test, keep in sync with Cargo.toml
+const REVM_VERSION: &str = "20.0.0-alpha.1";
+
+// Set in the response size when the output is an error message
+const RESPONSE_ERROR_FLAG: u32 = 1 << 31;
+
 fn process_input(method: &str, input: &[u8], is_execution: bool, reth: &Reth) -> Result<Vec<u8>, String> {
     if is_execution {
         // Handle precompile call
@@ -32,7 +38,7 @@ fn main() {
     println!("Connecting to driver...");
     let mut stream = UnixStream::connect(SOCKET_NAME).expect("Failed to connect to driver");
     stream
-        .write_all(b"rust")
+        .write_all(format!("rust@revm-{}", REVM_VERSION).as_bytes())
         .expect("Failed to send name to driver");
 
     // Attach to the input buffer
@@ -90,25 +96,23 @@ fn main() {
 
                 // Process the input in some way...
                 let start_time = Instant::now();
-                match process_input(method, input, true, &reth) {
-                    Ok(output) => {
-                        // Copy the output to the shared memory
-                        let output_shm: &mut [u8] =
-                            unsafe { slice::from_raw_parts_mut(shm_output_addr as *mut u8, output.len()) };
-                        output_shm.copy_from_slice(output.as_ref());
-
-                        // Send the processed data size back to the driver as 4 bytes
-                        let mut response_size_buffer = [0u8; 4];
-                        BigEndian::write_u32(&mut response_size_buffer, output.len() as u32);
-                        if let Err(e) = stream.write_all(&response_size_buffer) {
-                            println!("Failed to send response to driver: {}", e);
-                            break;
-                        }
-                    }
-                    Err(e) => {
-                        eprintln!("Error: {}", e);
-                        break;
-                    }
+                // Rejected inputs return the error message with the error flag set
+                let (output, flag) = match process_input(method, input, true, &reth) {
+                    Ok(output) => (output, 0),
+                    Err(e) => (e.into_bytes(), RESPONSE_ERROR_FLAG),
+                };
+
+                // Copy the output to the shared memory
+                let output_shm: &mut [u8] =
+                    unsafe { slice::from_raw_parts_mut(shm_output_addr as *mut u8, output.len()) };
+                output_shm.copy_from_slice(output.as_ref());
+
+                // Send the processed data size back to the driver as 4 bytes
+                let mut response_size_buffer = [0u8; 4];
+                BigEndian::write_u32(&mut response_size_buffer, output.len() as u32 | flag);
+                if let Err(e) = stream.write_all(&response_size_buffer) {
+                    println!("Failed to send response to driver: {}", e);
+                    break;
                 }
                 let elapsed_time = start_time.elapsed();
                 println!("Processing time: {:.2?}", elapsed_time);